@@ -0,0 +1,78 @@
+package main
+
+import (
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+)
+
+// makeAccessible sets up the --accessible mode for screen readers: no
+// borders, plain ASCII icons, the selected button and tab marked in text,
+// nothing moving, and what changes printed as lines of its own, see
+// announce. main turns off colors. Reloading the config makes it again.
+func (m *model) makeAccessible() {
+    m.accessible = true
+    m.setReducedMotion(true)
+    paneBorders = map[focusState]lipgloss.Border{focusList: {}, focusViewport: {}, focusInput: {}}
+    icons = iconSets["ascii"]
+    m.spinner.Spinner = icons.spinner
+    m.list.SetDelegate(customDelegate{cursor: true})
+    m.resizeTabs()
+}
+
+// announce prints text on a line of its own above the TUI in accessible
+// mode, where a screen reader reads it like the output of any program.
+func (m *model) announce(text string) tea.Cmd {
+    if !m.accessible {
+        return nil
+    }
+    return tea.Println(text)
+}
+
+// announced handles msg and announces in accessible mode where the focus
+// went, and which button or tab is selected as that changes.
+func (m model) announced(msg tea.Msg) (tea.Model, tea.Cmd) {
+    if !m.accessible {
+        return m.update(msg)
+    }
+    focus, button, tab := m.focus, m.selectedTitle(), m.currentTab
+    next, cmd := m.update(msg)
+    n, ok := next.(model)
+    if !ok {
+        return next, cmd
+    }
+    var text string
+    switch {
+    case n.focus != focus:
+        text = n.focusDescription()
+    case n.currentTab != tab:
+        text = "Tab " + n.tabs[n.currentTab].title
+    case n.focus == focusList && n.selectedTitle() != button:
+        text = n.selectedTitle()
+    }
+    if text != "" {
+        cmd = tea.Batch(cmd, n.announce(text))
+    }
+    return n, cmd
+}
+
+// focusDescription says which pane has the focus, and what is in it.
+func (m *model) focusDescription() string {
+    switch m.focus {
+    case focusList:
+        if title := m.selectedTitle(); title != "" {
+            return "Buttons, " + title + " selected"
+        }
+        return "Buttons, none shown"
+    case focusViewport:
+        return "Output of tab " + m.tabs[m.currentTab].title
+    }
+    return "Input, " + m.input.Placeholder
+}
+
+// selectedTitle returns the title of the selected button, or "" for none.
+func (m *model) selectedTitle() string {
+    if i, ok := m.list.SelectedItem().(listItem); ok {
+        return i.Title()
+    }
+    return ""
+}
@@ -0,0 +1,36 @@
+package main
+
+// extractAliases reads the aliases table. Each alias maps a word to the
+// command it stands for, given as a string or a list of arguments.
+func extractAliases(node configNode) map[string][]string {
+    aliases := make(map[string][]string)
+    for _, name := range node.keys() {
+        value := node.key(name)
+        if _, ok := value.value.([]interface{}); ok {
+            aliases[name] = value.strs()
+        } else if words, err := shellWords(value.str("")); err != nil {
+            value.fail(err.Error())
+            continue
+        } else {
+            aliases[name] = words
+        }
+        if len(aliases[name]) == 0 {
+            value.fail("must not be empty")
+            delete(aliases, name)
+        }
+    }
+    return aliases
+}
+
+// expandAlias replaces the first word of a typed command when it is an
+// alias. Aliases are expanded once, so one alias may not refer to another.
+func (m *model) expandAlias(argv []string) []string {
+    if len(argv) == 0 {
+        return argv
+    }
+    expansion, ok := m.aliases[argv[0]]
+    if !ok {
+        return argv
+    }
+    return append(append([]string(nil), expansion...), argv[1:]...)
+}
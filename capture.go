@@ -0,0 +1,77 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// captureSpec says where a button's output is kept for later commands.
+type captureSpec struct {
+    name    string         // Variable the output is kept in, "" to keep nothing
+    pattern *regexp.Regexp // Keeps the first match, or its first group, rather than the whole output
+}
+
+// variableName is what a captured variable may be called, so {name} can
+// refer to it.
+var variableName = regexp.MustCompile(`^\w+$`)
+
+// extractCapture reads a button's capture setting: the name of the variable
+// its output is kept in, or a table with the name and a pattern whose first
+// match, or the match's first group, is kept instead of the whole output.
+func extractCapture(node configNode) captureSpec {
+    if !node.isSet() {
+        return captureSpec{}
+    }
+    var spec captureSpec
+    if _, ok := node.value.(map[string]interface{}); ok {
+        spec = captureSpec{name: node.key("name").requiredStr(), pattern: node.key("pattern").regexp()}
+    } else {
+        spec.name = node.str("")
+    }
+    if spec.name != "" && !variableName.MatchString(spec.name) {
+        node.fail("must be a name made of letters, digits and underscores, got %q", spec.name)
+        return captureSpec{}
+    }
+    return spec
+}
+
+// captureOutput keeps the standard output of j in the variable its button
+// captures to, like $(...) in a shell. Nothing is kept when the pattern
+// doesn't match.
+func (m *model) captureOutput(j *job) {
+    name := j.cmd.capture.name
+    value := strings.TrimSpace(ansiEscape.ReplaceAllString(j.stdout.String(), ""))
+    if pattern := j.cmd.capture.pattern; pattern != nil {
+        match := pattern.FindStringSubmatch(value)
+        if match == nil {
+            m.appendOutput(j.tab, fmt.Sprintf("Nothing in the output matched %s, {%s} is unchanged\n", pattern, name))
+            return
+        }
+        value = match[0]
+        if len(match) > 1 {
+            value = match[1]
+        }
+    }
+    m.variables[name] = value
+    m.appendOutput(j.tab, fmt.Sprintf("Captured {%s} = %s\n", name, value))
+}
+
+// fillVariables replaces the {name} of each captured variable in cmd's
+// arguments with its value. It fails when cmd refers to a variable that a
+// button captures but that hasn't been captured yet.
+func (m *model) fillVariables(cmd command) (command, error) {
+    for name, value := range m.variables {
+        cmd = cmd.fill(name, value)
+    }
+    for _, arg := range cmd.cmd {
+        for _, match := range placeholderRef.FindAllStringSubmatch(arg, -1) {
+            for _, button := range m.configOrder() {
+                if button.capture.name == match[1] {
+                    return cmd, fmt.Errorf("{%s} is captured by %s, run it first", match[1], button.name)
+                }
+            }
+        }
+    }
+    return cmd, nil
+}
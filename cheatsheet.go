@@ -0,0 +1,188 @@
+package main
+
+import (
+    "strings"
+
+    "github.com/charmbracelet/bubbles/key"
+    "github.com/charmbracelet/lipgloss"
+)
+
+// cheatSheetBox frames the cheat sheet in the middle of the screen.
+var cheatSheetBox = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(accentColor).Padding(1, 2)
+
+// keyGroup is a titled group of bindings in the cheat sheet.
+type keyGroup struct {
+    title    string
+    bindings []key.Binding
+}
+
+// mouseBinding describes a mouse action in the cheat sheet, which only shows
+// the help of a binding.
+func mouseBinding(action, desc string) key.Binding {
+    return key.NewBinding(key.WithHelp(action, desc))
+}
+
+// keyGroups lists the bindings by the pane they act on, with the keys the
+// config chose. The list, output and input have built-in keys of their own.
+func (m *model) keyGroups() []keyGroup {
+    k := m.keys
+    listKeys := m.list.KeyMap
+    viewKeys := m.tabs[m.currentTab].viewport.KeyMap
+    output := []key.Binding{
+        viewKeys.Up, viewKeys.Down, viewKeys.PageUp, viewKeys.PageDown,
+        k.Filter, k.Search, k.NextMatch, k.PrevMatch,
+        k.PrevRun, k.NextRun, k.SetMark, k.JumpMark, k.ReplayNext, k.ReplayPrev,
+        k.Refresh, k.Pager, k.Export, k.Clear, k.UndoClear, k.Wrap, k.Streams, k.Fold, k.Diff,
+        mouseBinding("wheel", "scroll"),
+    }
+    if m.vimNavigation {
+        output = append(output,
+            key.NewBinding(key.WithHelp("gg/G", "top/bottom")),
+            key.NewBinding(key.WithHelp("ctrl+d/u", "half page down/up")),
+            key.NewBinding(key.WithHelp("zz", "center line")),
+            key.NewBinding(key.WithHelp("za", "fold run")),
+        )
+    }
+    input := []key.Binding{
+        key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run or send")),
+        key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete")),
+        key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "end stdin")),
+        key.NewBinding(key.WithKeys("+", "-"), key.WithHelp("+/-", "step a number")),
+        key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "step a number or time")),
+        key.NewBinding(key.WithKeys("ctrl+a", "ctrl+e"), key.WithHelp("ctrl+a/e", "start/end of line")),
+        key.NewBinding(key.WithKeys("alt+b", "alt+f"), key.WithHelp("alt+b/f", "word back/forward")),
+        key.NewBinding(key.WithKeys("ctrl+w"), key.WithHelp("ctrl+w", "delete word")),
+        key.NewBinding(key.WithKeys("ctrl+k", "ctrl+u"), key.WithHelp("ctrl+k/u", "delete to end/start")),
+        k.Editor,
+    }
+    if m.viInput {
+        input = append(input,
+            key.NewBinding(key.WithHelp("esc", "vi normal mode")),
+            key.NewBinding(key.WithHelp("i/a/I/A", "insert")),
+            key.NewBinding(key.WithHelp("w/b/e", "word motions")),
+            key.NewBinding(key.WithHelp("c/d/y", "change/delete/yank")),
+            key.NewBinding(key.WithHelp("ci\"/ciw", "change inside")),
+            key.NewBinding(key.WithHelp("p/u", "paste/undo")),
+        )
+    }
+    return []keyGroup{
+        {"General", []key.Binding{k.NextFocus, k.PrevFocus, k.Help, k.Quit, k.NextProfile, k.Kube}},
+        {"Buttons", []key.Binding{
+            listKeys.CursorUp, listKeys.CursorDown, listKeys.NextPage, listKeys.PrevPage, listKeys.Filter,
+            k.Execute, k.Pipe, k.Pin, k.Sort, k.Tag, k.Reload,
+            mouseBinding("click", "select"), mouseBinding("double click", "run"),
+        }},
+        {"Output", output},
+        {"Input", input},
+        {"Jobs", []key.Binding{k.Signal, k.Stdin}},
+        {"Tabs and layout", []key.Binding{
+            k.NextTab, k.PrevTab, k.ToggleSplit, k.SwitchPane, k.Compare, k.Zen,
+            k.GrowList, k.ShrinkList, k.GrowOutput, k.ShrinkOutput,
+            mouseBinding("click", "switch tab or pane"),
+        }},
+    }
+}
+
+// shortHelp returns the bindings for the help line: the ones of the
+// focused pane, followed by a few that work everywhere.
+func (m *model) shortHelp() []key.Binding {
+    k := m.keys
+    switch m.focus {
+    case focusList:
+        filter := m.list.KeyMap.Filter
+        filter.SetHelp(filter.Help().Key, "filter buttons")
+        return []key.Binding{k.Execute, k.Pipe, filter, k.Pin, k.Sort, k.Tag, k.NextFocus, k.NextTab, k.Help, k.Quit}
+    case focusViewport:
+        bindings := []key.Binding{k.Search}
+        if m.replay != nil {
+            bindings = []key.Binding{k.ReplayNext, k.ReplayPrev, k.Search}
+        }
+        if m.tabs[m.currentTab].search != "" {
+            bindings = append(bindings, k.NextMatch, k.PrevMatch)
+        }
+        bindings = append(bindings, k.Filter, k.Pager, k.Clear, k.Wrap, k.Streams, k.Fold, k.Refresh, k.NextFocus, k.NextTab, k.Help, k.Quit)
+        return bindings
+    }
+
+    enter := "run command"
+    switch {
+    case m.searching:
+        enter = "search"
+    case m.exporting:
+        enter = "export"
+    case m.confirming:
+        enter = "confirm"
+    case m.passwordJob != 0:
+        enter = "send password"
+    case m.prompInput:
+        enter = "run with argument"
+    case m.pipeSource != nil:
+        enter = "pipe into it"
+    case m.inputJob(m.currentTab) != nil:
+        enter = "send input"
+    }
+    bindings := []key.Binding{key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", enter))}
+    if m.viNormal {
+        bindings = append(bindings, key.NewBinding(key.WithKeys("i"), key.WithHelp("i/a", "insert")))
+    } else if m.viInput {
+        bindings = append(bindings, key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "normal mode")))
+    }
+    if spec, ok := m.prompted(); ok && spec.kind == "number" {
+        step := "step"
+        if bounds := spec.number.describe(); bounds != "" {
+            step += " (" + bounds + ")"
+        }
+        bindings = append(bindings, key.NewBinding(key.WithKeys("+", "-"), key.WithHelp("+/-", step)))
+    } else if ok && spec.kind == "time" {
+        bindings = append(bindings, key.NewBinding(key.WithKeys("up", "down"), key.WithHelp("↑/↓", "step "+spec.time.describe())))
+    }
+    if m.inputJob(m.currentTab) == nil && !m.searching && m.passwordJob == 0 {
+        bindings = append(bindings, key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete")))
+    }
+    if m.stdinMode {
+        bindings = append(bindings, key.NewBinding(key.WithKeys("ctrl+d"), key.WithHelp("ctrl+d", "end stdin")))
+    }
+    return append(bindings, k.Editor, k.Stdin, k.NextFocus, k.PrevFocus)
+}
+
+// cheatSheetView renders every binding, grouped by pane, as a box in the
+// middle of the screen.
+func (m *model) cheatSheetView() string {
+    keyStyle, descStyle := m.help.Styles.ShortKey, m.help.Styles.ShortDesc
+    var blocks []string
+    for _, group := range m.keyGroups() {
+        keyWidth := 0
+        for _, b := range group.bindings {
+            keyWidth = max(keyWidth, lipgloss.Width(b.Help().Key))
+        }
+        lines := []string{activeTab.Render(group.title)}
+        for _, b := range group.bindings {
+            if b.Help().Key == "" {
+                continue
+            }
+            lines = append(lines, " "+keyStyle.Width(keyWidth).Render(b.Help().Key)+"  "+descStyle.Render(b.Help().Desc))
+        }
+        blocks = append(blocks, lipgloss.NewStyle().MarginRight(4).MarginBottom(1).Render(strings.Join(lines, "\n")))
+    }
+
+    // Put as many groups side by side as the screen has room for
+    room := m.width - cheatSheetBox.GetHorizontalFrameSize()
+    var rows, row []string
+    rowWidth := 0
+    for _, block := range blocks {
+        if len(row) > 0 && m.width > 0 && rowWidth+lipgloss.Width(block) > room {
+            rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+            row, rowWidth = nil, 0
+        }
+        row = append(row, block)
+        rowWidth += lipgloss.Width(block)
+    }
+    rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+
+    footer := descStyle.Render(" Press " + m.keys.Help.Help().Key + " or esc to close")
+    sheet := cheatSheetBox.Render(lipgloss.JoinVertical(lipgloss.Left, append(rows, footer)...))
+    if m.width == 0 {
+        return sheet
+    }
+    return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, sheet)
+}
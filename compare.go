@@ -0,0 +1,121 @@
+package main
+
+import (
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/x/ansi"
+)
+
+// compareInterval is how often at most a comparison is redrawn while output
+// arrives in the compared tabs.
+const compareInterval = 100 * time.Millisecond
+
+// compareRefreshMsg redraws a comparison that output arrived for.
+type compareRefreshMsg struct{}
+
+// toggleCompare switches compare mode, which shows the two tabs of a side
+// by side split scrolling together, with the lines that differ between them
+// highlighted. Starting it without a split splits the current tab with the
+// next one.
+func (m *model) toggleCompare() {
+    if len(m.tabs) < 2 {
+        return
+    }
+    m.compare = !m.compare
+    if m.compare && m.split != splitSideBySide {
+        m.split = splitSideBySide
+        m.splitTabs = [2]int{m.currentTab, (m.currentTab + 1) % len(m.tabs)}
+        m.splitPane = 0
+    }
+    // Puts the plain output back when compare mode ends
+    m.resizeTabs()
+}
+
+// isCompared reports whether tab is one of the two being compared.
+func (m *model) isCompared(tab int) bool {
+    return m.compare && m.split == splitSideBySide && (m.splitTabs[0] == tab || m.splitTabs[1] == tab)
+}
+
+// showComparison fills the panes of the compared tabs with their output,
+// lines that were removed on the left or added on the right highlighted.
+// Long lines are cut off rather than wrapped so lines stay side by side.
+func (m *model) showComparison() {
+    if !m.isCompared(m.splitTabs[0]) {
+        return
+    }
+    left, right := &m.tabs[m.splitTabs[0]], &m.tabs[m.splitTabs[1]]
+    a, b := outputLines(left.text()), outputLines(right.text())
+    changedA, changedB := make([]bool, len(a)), make([]bool, len(b))
+    i, j := 0, 0
+    for _, op := range diffLines(a, b) {
+        switch op.kind {
+        case '-':
+            changedA[i] = true
+            i++
+        case '+':
+            changedB[j] = true
+            j++
+        default:
+            i, j = i+1, j+1
+        }
+    }
+    left.viewport.SetContent(compareLines(a, changedA, left.viewport.Width, diffRemoved.Render))
+    right.viewport.SetContent(compareLines(b, changedB, right.viewport.Width, diffAdded.Render))
+    m.syncScroll()
+}
+
+// compareTick schedules redrawing the comparison after output arrived for
+// it, once per compareInterval however much arrives.
+func (m *model) compareTick() tea.Cmd {
+    if len(m.compareGrown) == 0 || m.compareTicking {
+        return nil
+    }
+    m.compareTicking = true
+    return tea.Tick(compareInterval, func(time.Time) tea.Msg { return compareRefreshMsg{} })
+}
+
+// refreshComparison redraws the comparison with the output that arrived,
+// scrolled to the end of it like appendOutput does.
+func (m *model) refreshComparison() {
+    m.compareTicking = false
+    grown := m.compareGrown
+    m.compareGrown = nil
+    for tab := range grown {
+        if !m.isCompared(tab) {
+            // Compare mode ended meanwhile, which showed the output
+            return
+        }
+    }
+    m.showComparison()
+    for tab := range grown {
+        m.tabs[tab].viewport.GotoBottom()
+    }
+    m.syncScroll()
+}
+
+// compareLines fits lines to width, highlighting the changed ones.
+func compareLines(lines []string, changed []bool, width int, highlight func(...string) string) string {
+    var b strings.Builder
+    for i, line := range lines {
+        if width > 0 && ansi.StringWidth(line) > width {
+            line = ansi.Truncate(line, width, "…")
+        }
+        if changed[i] {
+            line = highlight(line)
+        }
+        b.WriteString(line + "\n")
+    }
+    return b.String()
+}
+
+// syncScroll scrolls the other compared pane to the same line as the
+// current tab.
+func (m *model) syncScroll() {
+    if !m.isCompared(m.currentTab) {
+        return
+    }
+    other := m.splitTabs[1-m.splitPane]
+    m.tabs[other].viewport.SetYOffset(m.tabs[m.currentTab].viewport.YOffset)
+}
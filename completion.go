@@ -0,0 +1,67 @@
+package main
+
+import (
+    "fmt"
+
+    lua "github.com/yuin/gopher-lua"
+)
+
+// completionSource supplies the suggestions cycled with tab in the input
+// box: either a fixed list or a Lua function called with what has been
+// typed so far, returning a list of suggestions.
+type completionSource struct {
+    list []string
+    fn   *lua.LFunction
+}
+
+func (c completionSource) isSet() bool {
+    return len(c.list) > 0 || c.fn != nil
+}
+
+// extractCompletions reads a completions value, a list of strings or, in
+// Lua configs, a function.
+func extractCompletions(node configNode) completionSource {
+    if fn, ok := node.value.(*lua.LFunction); ok {
+        return completionSource{fn: fn}
+    }
+    return completionSource{list: node.strs()}
+}
+
+// complete returns the suggestions for prefix.
+func (m *model) complete(source completionSource, prefix string) []string {
+    if source.fn == nil {
+        return source.list
+    }
+    if m.script == nil {
+        return nil
+    }
+    suggestions, err := m.script.complete(source.fn, prefix)
+    if err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error in completions: %v\n", err))
+    }
+    return suggestions
+}
+
+// nextCompletion fills the input with the next suggestion. The first tab
+// asks the source for suggestions based on the input, later ones cycle
+// through them until something else is typed.
+func (m *model) nextCompletion() {
+    if m.suggestions == nil {
+        // Buttons that prompt can offer suggestions for their argument
+        source := m.completions
+        if m.prompInput && m.currentIndex >= 0 && m.currentIndex < len(m.commands) {
+            if c := m.commands[m.currentIndex].completions; c.isSet() {
+                source = c
+            }
+        }
+        m.suggestions = m.complete(source, m.input.Value())
+        m.suggestion = -1
+    }
+    if len(m.suggestions) == 0 {
+        m.suggestions = nil
+        return
+    }
+    m.suggestion = (m.suggestion + 1) % len(m.suggestions)
+    m.input.SetValue(m.suggestions[m.suggestion])
+    m.input.CursorEnd()
+}
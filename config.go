@@ -0,0 +1,431 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/BurntSushi/toml"
+    "github.com/charmbracelet/lipgloss"
+    lua "github.com/yuin/gopher-lua"
+    "gopkg.in/yaml.v3"
+)
+
+// config holds everything read from the config file.
+type config struct {
+    commands       []command
+    vpDimensions   dimensions
+    listDimensions dimensions
+    tiDimensions   dimensions
+    completions    completionSource
+    profiles       []profile
+    profile        int // Index of the profile to start with, -1 for none
+    tabs           []tabConfig
+    keys           map[string][]string            // Key overrides by binding name, see keyMap.bindings
+    theme          theme                          // Style changes made before those of styles
+    styles         map[string][]styleSetter       // Style changes by style name, see styles
+    borders        map[focusState]lipgloss.Border // Borders of the panes, see paneStyle
+    icons          iconSet                        // What the status of commands is shown with
+    aliases        map[string][]string            // Words expanded at the start of typed commands
+    targets        map[string]string              // Hosts buttons can run on, by name
+    tmuxTarget     string                         // tmux pane commands are typed into by default
+    httpToken      string                         // Token --http requests need, made up when empty
+    bellOnFailure  bool // Ring the terminal bell when a command fails
+    flashOnFailure bool // Briefly flash the tab and output border red when a command fails
+    glob           bool // Expand *.log style arguments of buttons that don't say otherwise
+    reducedMotion  bool // No spinner, sliding progress bar, blinking or flashing
+    vimNavigation  bool // The output takes vim's gg, G, ctrl+d, ctrl+u, zz and za
+    viInput        bool // The input is edited like vi, with normal and insert modes
+    tabMemory      int  // Megabytes of output each tab keeps in memory, the rest is moved to disk
+    script         *scripting   // Lua state kept alive for hooks, nil for other formats
+    generators     []*generator // Make buttons from the output of commands, see generator
+    files          []string     // The config file and every file it includes, for reloading
+    dirs           []string     // Script directories, reloaded when scripts are added or removed
+}
+
+// configLoader decodes a config file into a generic tree of
+// map[string]interface{}, []interface{} and scalar values, so every format
+// shares the same schema and is turned into a config by buildConfig. Loaders
+// for scriptable formats run the file in script, or a new state when it is
+// nil, and return the state used. Other loaders return script unchanged.
+type configLoader func(path string, script *scripting) (map[string]interface{}, *scripting, error)
+
+// configLoaders maps a config file extension to the loader for its format.
+var configLoaders = map[string]configLoader{
+    ".lua":  loadLuaConfig,
+    ".yaml": loadYAMLConfig,
+    ".yml":  loadYAMLConfig,
+    ".toml": loadTOMLConfig,
+    ".json": loadJSONConfig,
+}
+
+// configFileNames are looked up, in order, in each of the default config locations.
+var configFileNames = []string{"config.lua", "config.yaml", "config.yml", "config.toml", "config.json"}
+
+// findConfig returns the config file to load. An explicit path always wins,
+// otherwise the working directory, $XDG_CONFIG_HOME/cmdtui and
+// ~/.config/cmdtui are searched in that order.
+func findConfig(explicit string) (string, error) {
+    if explicit != "" {
+        if _, err := os.Stat(explicit); err != nil {
+            return "", err
+        }
+        return explicit, nil
+    }
+
+    dirs := []string{"."}
+    if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+        dirs = append(dirs, filepath.Join(xdg, "cmdtui"))
+    }
+    if home, err := os.UserHomeDir(); err == nil {
+        dirs = append(dirs, filepath.Join(home, ".config", "cmdtui"))
+    }
+
+    var candidates []string
+    for _, dir := range dirs {
+        for _, name := range configFileNames {
+            path := filepath.Join(dir, name)
+            if _, err := os.Stat(path); err == nil {
+                return path, nil
+            }
+            candidates = append(candidates, path)
+        }
+    }
+    return "", fmt.Errorf("no config file found, looked in: %s", strings.Join(candidates, ", "))
+}
+
+// loadConfig reads the config at path, and any files it includes, using the
+// loader matching each file's extension.
+func loadConfig(path string) (config, error) {
+    raw, script, err := loadConfigFile(path, nil)
+    if err != nil {
+        return config{}, err
+    }
+    cfg, err := buildConfig(path, raw, script)
+    if err != nil {
+        if cfg.script != nil {
+            cfg.script.close()
+        }
+        return config{}, err
+    }
+    return cfg, nil
+}
+
+// loadConfigFile decodes a single config file into the generic tree.
+func loadConfigFile(path string, script *scripting) (map[string]interface{}, *scripting, error) {
+    loader, ok := configLoaders[strings.ToLower(filepath.Ext(path))]
+    if !ok {
+        return nil, script, fmt.Errorf("%s: unsupported config format %q", path, filepath.Ext(path))
+    }
+    return loader(path, script)
+}
+
+// loadLuaConfig runs a Lua config. The state stays open afterwards so hooks
+// and functions defined by the config can be called later.
+func loadLuaConfig(path string, script *scripting) (map[string]interface{}, *scripting, error) {
+    created := script == nil
+    if created {
+        script = newScripting(path)
+    }
+    fail := func(err error) (map[string]interface{}, *scripting, error) {
+        if created {
+            script.close()
+            script = nil
+        }
+        return nil, script, err
+    }
+
+    if err := script.L.DoFile(path); err != nil {
+        return fail(err)
+    }
+    raw, ok := luaToGo(script.L.Get(-1)).(map[string]interface{})
+    script.L.Pop(1)
+    if !ok {
+        return fail(fmt.Errorf("%s: config must return a table", path))
+    }
+    return raw, script, nil
+}
+
+func loadYAMLConfig(path string, script *scripting) (map[string]interface{}, *scripting, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, script, err
+    }
+    var raw map[string]interface{}
+    if err := yaml.Unmarshal(data, &raw); err != nil {
+        return nil, script, fmt.Errorf("%s: %v", path, err)
+    }
+    return raw, script, nil
+}
+
+func loadTOMLConfig(path string, script *scripting) (map[string]interface{}, *scripting, error) {
+    var raw map[string]interface{}
+    if _, err := toml.DecodeFile(path, &raw); err != nil {
+        return nil, script, fmt.Errorf("%s: %v", path, err)
+    }
+    return normalizeTOML(raw).(map[string]interface{}), script, nil
+}
+
+func loadJSONConfig(path string, script *scripting) (map[string]interface{}, *scripting, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, script, err
+    }
+    var raw map[string]interface{}
+    if err := json.Unmarshal(data, &raw); err != nil {
+        return nil, script, fmt.Errorf("%s: %v", path, err)
+    }
+    return raw, script, nil
+}
+
+// luaToGo converts a Lua value into the generic config tree. Empty tables and
+// tables with only sequential integer keys become slices, all other tables
+// become maps.
+func luaToGo(value lua.LValue) interface{} {
+    switch v := value.(type) {
+    case *lua.LNilType:
+        return nil
+    case lua.LBool:
+        return bool(v)
+    case lua.LNumber:
+        return float64(v)
+    case lua.LString:
+        return string(v)
+    case *lua.LTable:
+        n := v.MaxN()
+        count := 0
+        v.ForEach(func(_, _ lua.LValue) { count++ })
+        if n == count {
+            list := make([]interface{}, 0, n)
+            for i := 1; i <= n; i++ {
+                list = append(list, luaToGo(v.RawGetInt(i)))
+            }
+            return list
+        }
+        table := make(map[string]interface{}, count)
+        v.ForEach(func(k, item lua.LValue) {
+            table[k.String()] = luaToGo(item)
+        })
+        return table
+    default:
+        return v
+    }
+}
+
+// normalizeTOML rewrites the typed slices the TOML decoder produces for
+// arrays of tables into the []interface{} the rest of the loader expects.
+func normalizeTOML(value interface{}) interface{} {
+    switch v := value.(type) {
+    case map[string]interface{}:
+        for k, item := range v {
+            v[k] = normalizeTOML(item)
+        }
+        return v
+    case []map[string]interface{}:
+        list := make([]interface{}, len(v))
+        for i, item := range v {
+            list[i] = normalizeTOML(item)
+        }
+        return list
+    case []interface{}:
+        for i, item := range v {
+            v[i] = normalizeTOML(item)
+        }
+        return v
+    default:
+        return v
+    }
+}
+
+// Defaults for the optional layout sections.
+var (
+    defaultViewport = dimensions{width: 110, height: 20}
+    defaultList     = dimensions{width: 45, height: 20}
+)
+
+// buildConfig turns the generic config tree read from file into a config,
+// validating every value, merging included files and filling in defaults
+// for optional sections. The returned config carries the script even when
+// there are errors so the caller can close it.
+func buildConfig(file string, raw map[string]interface{}, script *scripting) (config, error) {
+    r := newConfigReader(file)
+    root := r.root(raw)
+
+    cfg := config{
+        commands:       extractCommands(root.key("buttons")),
+        vpDimensions:   extractDimensions(root.key("viewport"), defaultViewport),
+        listDimensions: extractDimensions(root.key("list"), defaultList),
+        completions:    extractCompletions(root.key("completions")),
+        tabs:           extractTabs(root.key("tabs")),
+        keys:           extractKeys(root.key("keys")),
+        theme:          extractTheme(root.key("theme")),
+        styles:         extractStyles(root.key("styles")),
+        borders:        extractBorders(root.key("borders")),
+        icons:          extractIcons(root.key("icons")),
+        aliases:        extractAliases(root.key("aliases")),
+        targets:        extractTargets(root.key("targets")),
+        tmuxTarget:     root.key("tmux_target").str(""),
+        httpToken:      root.key("http_token").str(""),
+        bellOnFailure:  root.key("bell_on_failure").boolean(false),
+        flashOnFailure: root.key("flash_on_failure").boolean(false),
+        glob:           root.key("glob").boolean(false),
+        reducedMotion:  root.key("reduced_motion").boolean(false),
+        vimNavigation:  root.key("vim_navigation").boolean(false),
+        viInput:        root.key("vi_input").boolean(false),
+        tabMemory:      root.key("tab_memory_mb").positiveInt(defaultTabMemory),
+        profiles:       extractProfiles(root.key("profiles")),
+        generators:     extractGenerators(root.key("generators")),
+        profile:        -1,
+        script:         script,
+        files:          []string{file},
+    }
+    cfg.include(root.key("include"), file)
+    cfg.importButtons(root.key("import"))
+    cfg.scriptButtons(root.key("script_dirs"), file)
+
+    // Start in the named profile, or the first one
+    if name := root.key("profile").str(""); name != "" {
+        if cfg.profile = findProfile(cfg.profiles, name); cfg.profile < 0 {
+            root.key("profile").fail("no profile named %q", name)
+        }
+    } else if len(cfg.profiles) > 0 {
+        cfg.profile = 0
+    }
+
+    textinput := root.key("textinput")
+    textinput.table()
+    cfg.tiDimensions = dimensions{width: textinput.key("width").positiveInt(cfg.vpDimensions.width - 3), height: 1}
+
+    if len(cfg.tabs) == 0 {
+        for _, title := range initTabs() {
+            cfg.tabs = append(cfg.tabs, tabConfig{title: title})
+        }
+    }
+
+    if err := r.err(); err != nil {
+        return config{script: cfg.script}, err
+    }
+    return cfg, nil
+}
+
+func extractCommands(buttons configNode) []command {
+    var commands []command
+    for _, button := range buttons.items() {
+        if cmd, ok := extractCommand(button); ok {
+            commands = append(commands, cmd)
+        }
+    }
+    return commands
+}
+
+// extractCommand reads a single button table.
+func extractCommand(button configNode) (command, bool) {
+    if !button.table() {
+        if !button.isSet() {
+            button.fail("expected a table, got nothing")
+        }
+        return command{}, false
+    }
+    name := button.key("name").requiredStr()
+    button = button.anchored(name)
+
+    cmdNode := button.key("cmd")
+    cmd := cmdNode.strs()
+    if !cmdNode.isSet() {
+        cmdNode.fail("missing required value")
+    } else if _, isList := cmdNode.value.([]interface{}); isList && len(cmd) == 0 {
+        cmdNode.fail("must contain at least the program to run")
+    }
+
+    var notify bool
+    var notifyAfter time.Duration
+    switch n := button.key("notify"); n.value.(type) {
+    case nil:
+    case bool:
+        notify = n.boolean(false)
+    default:
+        notify = true
+        notifyAfter = time.Duration(n.number(0) * float64(time.Second))
+    }
+
+    var glob *bool
+    if g := button.key("glob"); g.isSet() {
+        expand := g.boolean(false)
+        glob = &expand
+    }
+
+    prompt, spec := extractPrompt(button.key("prompt"))
+    return command{
+        name:        name,
+        cmd:         cmd,
+        prompt:      prompt,
+        promptSpec:  spec,
+        progress:    button.key("progress").regexp(),
+        notify:      notify,
+        notifyAfter: notifyAfter,
+        dir:         button.key("cwd").str(""),
+        env:         extractEnv(button.key("env")),
+        transform:   button.key("transform").function(),
+        completions: extractCompletions(button.key("completions")),
+        pty:         button.key("pty").boolean(false),
+        interactive: button.key("interactive").boolean(false),
+        input:       button.key("input").boolean(false),
+        sudo:        button.key("sudo").boolean(false),
+        target:      button.key("target").str(""),
+        tmuxTarget:  button.key("tmux_target").str(""),
+        renderer:    extractRenderer(button.key("render")),
+        watchPaths:  button.key("watch_paths").strs(),
+        group:       button.key("group").str(""),
+        description: button.key("description").str(""),
+        tags:        button.key("tags").strs(),
+        glob:        glob,
+        capture:     extractCapture(button.key("capture")),
+        enabled:     button.key("enabled").function(),
+        confirm:     button.key("confirm_phrase").str(""),
+        icon:        button.key("icon").str(""),
+        plainIcon:   button.key("icon_fallback").str(""),
+        stdin:       extractStdin(button.key("stdin")),
+    }, true
+}
+
+// extractEnv reads a table of environment variables as sorted KEY=VALUE pairs.
+func extractEnv(env configNode) []string {
+    var pairs []string
+    for _, name := range env.keys() {
+        pairs = append(pairs, name+"="+env.key(name).str(""))
+    }
+    sort.Strings(pairs)
+    return pairs
+}
+
+// extractKeys reads the optional keys section, where each binding name maps
+// to a single key or a list of keys.
+func extractKeys(section configNode) map[string][]string {
+    known := keys.bindings()
+    overrides := make(map[string][]string)
+    for _, name := range section.keys() {
+        binding := section.key(name)
+        if _, ok := known[name]; !ok {
+            binding.fail("unknown binding")
+            continue
+        }
+        if _, ok := binding.value.([]interface{}); ok {
+            overrides[name] = binding.strs()
+        } else {
+            overrides[name] = []string{binding.str("")}
+        }
+    }
+    return overrides
+}
+
+func extractDimensions(dim configNode, def dimensions) dimensions {
+    dim.table()
+    return dimensions{
+        width:  dim.key("width").positiveInt(def.width),
+        height: dim.key("height").positiveInt(def.height),
+    }
+}
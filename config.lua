@@ -18,6 +18,7 @@ return {
         { name = "Print Working Directory", cmd = {"pwd"}, prompt = false },
         { name = "Date", cmd = {"date"}, prompt = false },
         { name = "python test", cmd = {"python3", "test.py"}, prompt = true },
+        { name = "Progress Demo", cmd = {"sh", "-c", "for i in 20 40 60 80 100; do echo \"$i%\"; sleep 0.5; done"}, prompt = false, progress = "(\\d+)%" },
     },
     viewport = {
         width = 110,
@@ -30,5 +31,8 @@ return {
     textinput = {
         width = 110-3,
     },
-    completions = completions
+    completions = completions,
+    tabs = {"Main", "Tab 2", "Tab 3"},
+    bell_on_failure = true,
+    flash_on_failure = true
 }
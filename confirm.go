@@ -0,0 +1,72 @@
+package main
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// startButton runs the button at idx. Buttons with a confirm_phrase first
+// wait for the phrase to be typed exactly, as cloud consoles do before
+// deleting something, and then ask for their argument like the others.
+func (m *model) startButton(idx int) tea.Cmd {
+    if m.commands[idx].confirm != "" {
+        m.askPhrase(idx)
+        return nil
+    }
+    return m.runAt(idx)
+}
+
+// askPhrase focuses the input so the user can type the confirm phrase of the
+// button at idx.
+func (m *model) askPhrase(idx int) {
+    cmd := m.commands[idx]
+    m.resetInput()
+    m.input.Placeholder = fmt.Sprintf("Type %q to run %s...", cmd.confirm, cmd.name)
+    m.input.Focus()
+    m.focus = focusInput
+    m.confirming = true
+    m.currentIndex = idx
+}
+
+// checkPhrase runs the button waiting for its confirm phrase if typed is
+// the phrase, otherwise it keeps asking.
+func (m *model) checkPhrase(typed string) tea.Cmd {
+    idx := m.currentIndex
+    if idx < 0 || idx >= len(m.commands) {
+        m.resetInput()
+        m.focus = focusList
+        return nil
+    }
+    cmd := m.commands[idx]
+    if typed != cmd.confirm {
+        m.input.SetValue("")
+        m.promptError = fmt.Sprintf("Type %q exactly to run %s", cmd.confirm, cmd.name)
+        return nil
+    }
+    m.resetInput()
+    m.focus = focusList
+    return m.runAt(idx)
+}
+
+// headlessConfirm asks for the confirm phrase of cmd on stderr and reads it
+// from in, failing unless it is typed exactly.
+func headlessConfirm(cmd command, in *bufio.Reader) error {
+    if cmd.confirm == "" {
+        return nil
+    }
+    fmt.Fprintf(os.Stderr, "Type %q to run %s: ", cmd.confirm, cmd.name)
+    line, err := in.ReadString('\n')
+    if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+        return fmt.Errorf("reading the confirm phrase for %s: %v", cmd.name, err)
+    }
+    if strings.TrimRight(line, "\r\n") != cmd.confirm {
+        return fmt.Errorf("%s not confirmed", cmd.name)
+    }
+    return nil
+}
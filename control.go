@@ -0,0 +1,204 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// controlServer lets other programs, such as editors, git hooks or scripts,
+// drive cmdtui through a unix socket or over HTTP. On the socket each line
+// sent is a request and is answered with a line starting with "ok" or
+// "error:". Requests are:
+//
+//    run <button>               run a button, as if enter was pressed on it
+//    append-output <tab> <text> append text to a tab, by number or title
+//    status                     running jobs and last exit codes as JSON
+//
+// For example: echo "run Build" | nc -U ~/.cache/cmdtui.sock
+type controlServer struct {
+    listeners []net.Listener
+    requests  chan controlRequestMsg
+    done      chan struct{} // Closed once cmdtui stops taking requests
+    tokenFile string        // HTTP token made up for this session, removed on close
+}
+
+// controlTimeout is how long a request waits for cmdtui to take it and
+// reply before giving up.
+const controlTimeout = 10 * time.Second
+
+// errControlUnavailable is returned for requests cmdtui didn't answer, as it
+// quit or was busy for longer than controlTimeout.
+var errControlUnavailable = errors.New("cmdtui did not answer, it quit or is busy")
+
+// controlRequestMsg is a request from another program. The reply is sent
+// back to it once the request has been handled.
+type controlRequestMsg struct {
+    line  string
+    reply chan string
+}
+
+func newControlServer() *controlServer {
+    return &controlServer{requests: make(chan controlRequestMsg), done: make(chan struct{})}
+}
+
+// listenUnix starts accepting requests on the socket at path. A socket left
+// behind by a cmdtui that is no longer running is replaced.
+func (s *controlServer) listenUnix(path string) error {
+    if path == "~" || strings.HasPrefix(path, "~/") {
+        home, err := os.UserHomeDir()
+        if err != nil {
+            return err
+        }
+        path = filepath.Join(home, path[1:])
+    }
+    if conn, err := net.Dial("unix", path); err == nil {
+        conn.Close()
+        return fmt.Errorf("%s is in use by another cmdtui", path)
+    }
+    os.Remove(path)
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+
+    listener, err := net.Listen("unix", path)
+    if err != nil {
+        return err
+    }
+    s.listeners = append(s.listeners, listener)
+    go s.accept(listener)
+    return nil
+}
+
+func (s *controlServer) accept(listener net.Listener) {
+    for {
+        conn, err := listener.Accept()
+        if err != nil {
+            // Closed when cmdtui exits
+            return
+        }
+        go s.serve(conn)
+    }
+}
+
+// serve passes each request on conn to the update loop and writes back the
+// replies in order.
+func (s *controlServer) serve(conn net.Conn) {
+    defer conn.Close()
+    scanner := bufio.NewScanner(conn)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" {
+            continue
+        }
+        reply, err := s.request(line)
+        if err != nil {
+            reply = "error: " + err.Error()
+        }
+        if _, err := fmt.Fprintln(conn, reply); err != nil {
+            return
+        }
+    }
+}
+
+// request hands a request to the update loop and waits for the reply, for
+// at most controlTimeout.
+func (s *controlServer) request(line string) (string, error) {
+    // Buffered so a reply that comes too late doesn't block the update loop
+    reply := make(chan string, 1)
+    timeout := time.NewTimer(controlTimeout)
+    defer timeout.Stop()
+    select {
+    case s.requests <- controlRequestMsg{line: line, reply: reply}:
+    case <-s.done:
+        return "", errControlUnavailable
+    case <-timeout.C:
+        return "", errControlUnavailable
+    }
+    select {
+    case r := <-reply:
+        return r, nil
+    case <-s.done:
+        return "", errControlUnavailable
+    case <-timeout.C:
+        return "", errControlUnavailable
+    }
+}
+
+// close stops listening, which removes the socket, fails the requests still
+// waiting, and removes the HTTP token made up for the session.
+func (s *controlServer) close() {
+    close(s.done)
+    for _, listener := range s.listeners {
+        listener.Close()
+    }
+    if s.tokenFile != "" {
+        os.Remove(s.tokenFile)
+    }
+}
+
+// wait returns a tea.Cmd that delivers the next request.
+func (s *controlServer) wait() tea.Cmd {
+    return func() tea.Msg {
+        return <-s.requests
+    }
+}
+
+// handleControl carries out a request from another program and replies to it.
+func (m *model) handleControl(msg controlRequestMsg) tea.Cmd {
+    verb, rest, _ := strings.Cut(msg.line, " ")
+    rest = strings.TrimSpace(rest)
+
+    switch verb {
+    case "run":
+        for idx, cmd := range m.commands {
+            if cmd.name != rest {
+                continue
+            }
+            msg.reply <- "ok"
+            return m.startButton(idx)
+        }
+        msg.reply <- fmt.Sprintf("error: no button named %q", rest)
+    case "append-output":
+        name, text, _ := strings.Cut(rest, " ")
+        tab, err := m.tabNamed(name)
+        if err != nil {
+            msg.reply <- "error: " + err.Error()
+            return nil
+        }
+        m.appendOutput(tab, text+"\n")
+        msg.reply <- "ok"
+    case "status":
+        status, err := json.Marshal(m.status())
+        if err != nil {
+            msg.reply <- "error: " + err.Error()
+            return nil
+        }
+        msg.reply <- "ok " + string(status)
+    default:
+        msg.reply <- fmt.Sprintf("error: unknown request %q, expected run, append-output or status", verb)
+    }
+    return nil
+}
+
+// tabNamed finds a tab by its 1-based number or its title.
+func (m *model) tabNamed(name string) (int, error) {
+    if n, err := strconv.Atoi(name); err == nil && n >= 1 && n <= len(m.tabs) {
+        return n - 1, nil
+    }
+    for i, tab := range m.tabs {
+        if tab.title == name {
+            return i, nil
+        }
+    }
+    return 0, fmt.Errorf("no tab %s", name)
+}
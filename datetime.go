@@ -0,0 +1,112 @@
+package main
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// timeFormats are names for the common layouts of a time prompt.
+var timeFormats = map[string]string{
+    "rfc3339":  time.RFC3339,
+    "datetime": "2006-01-02 15:04",
+    "date":     time.DateOnly,
+    "time":     "15:04",
+}
+
+// timeSpec describes the argument of a prompt with type time. The prompt
+// starts at the default, now or an offset from it like -24h, and the arrow
+// keys move it by a step. The format is one of timeFormats, unix for seconds
+// since 1970, or a Go time layout.
+type timeSpec struct {
+    format  string
+    step    time.Duration
+    initial time.Duration // From now
+}
+
+// extractTime reads the format, step and default of a time prompt. The step
+// defaults to the smallest of a minute, an hour or a day the format shows.
+func extractTime(node configNode) timeSpec {
+    spec := timeSpec{format: node.key("format").str("datetime")}
+    if layout, ok := timeFormats[spec.format]; ok {
+        spec.format = layout
+    }
+    spec.step = node.key("step").duration(spec.shownStep())
+    if spec.step <= 0 {
+        node.key("step").fail("must be longer than zero")
+        spec.step = spec.shownStep()
+    }
+    if d := node.key("default"); d.str("now") != "now" {
+        spec.initial = d.duration(0)
+    }
+    return spec
+}
+
+// shownStep returns the smallest of a minute, an hour or a day that changes
+// the formatted time.
+func (spec timeSpec) shownStep() time.Duration {
+    base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+    for _, step := range []time.Duration{time.Minute, time.Hour} {
+        if spec.formatTime(base.Add(step)) != spec.formatTime(base) {
+            return step
+        }
+    }
+    return 24 * time.Hour
+}
+
+// formatTime writes t the way it is passed to the command.
+func (spec timeSpec) formatTime(t time.Time) string {
+    if spec.format == "unix" {
+        return strconv.FormatInt(t.Unix(), 10)
+    }
+    return t.Format(spec.format)
+}
+
+// parse reads a time typed in the format, in the local time zone unless it
+// says otherwise.
+func (spec timeSpec) parse(value string) (time.Time, error) {
+    if spec.format == "unix" {
+        secs, err := strconv.ParseInt(value, 10, 64)
+        return time.Unix(secs, 0), err
+    }
+    return time.ParseInLocation(spec.format, value, time.Local)
+}
+
+// start returns the time the prompt starts at.
+func (spec timeSpec) start() string {
+    return spec.formatTime(time.Now().Add(spec.initial))
+}
+
+// check returns why value is not a time in the format, or "" when it is.
+func (spec timeSpec) check(value string) string {
+    if _, err := spec.parse(value); err != nil {
+        return "must be a time like " + spec.start()
+    }
+    return ""
+}
+
+// stepped returns value moved by dir steps. Whole days are counted on the
+// calendar, so they keep the time of day across daylight saving changes. A
+// value that is not a time starts over from the default.
+func (spec timeSpec) stepped(value string, dir int) string {
+    t, err := spec.parse(value)
+    if err != nil {
+        return spec.start()
+    }
+    if spec.step%(24*time.Hour) == 0 {
+        return spec.formatTime(t.AddDate(0, 0, dir*int(spec.step/(24*time.Hour))))
+    }
+    return spec.formatTime(t.Add(time.Duration(dir) * spec.step))
+}
+
+// describe returns the step for the help line, like 1h, 15m or 2 days.
+func (spec timeSpec) describe() string {
+    if spec.step%(24*time.Hour) == 0 {
+        if days := spec.step / (24 * time.Hour); days > 1 {
+            return fmt.Sprintf("%d days", days)
+        }
+        return "1 day"
+    }
+    return strings.TrimSuffix(strings.TrimSuffix(spec.step.String(), "0s"), "0m")
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/charmbracelet/lipgloss"
+)
+
+// diffContext is how many unchanged lines are shown around each change.
+const diffContext = 3
+
+// maxDiffEdits bounds the work spent on outputs with little in common. Past
+// it, the whole output is shown as replaced.
+const maxDiffEdits = 2000
+
+var (
+    diffAdded   = lipgloss.NewStyle().Foreground(successColor)
+    diffRemoved = lipgloss.NewStyle().Foreground(failureColor)
+    diffHunk    = lipgloss.NewStyle().Foreground(infoColor)
+)
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+    kind byte
+    line string
+}
+
+// rememberRun keeps the output of the last two runs of the button called
+// name, for diffRuns.
+func (m *model) rememberRun(name, output string) {
+    runs := append(m.runOutputs[name], output)
+    if len(runs) > 2 {
+        runs = runs[len(runs)-2:]
+    }
+    m.runOutputs[name] = runs
+}
+
+// diffRuns writes a diff of the last two runs of the selected button to the
+// current tab.
+func (m *model) diffRuns() {
+    idx, ok := m.selectedIndex()
+    if !ok {
+        return
+    }
+    name := m.commands[idx].name
+    runs := m.runOutputs[name]
+    if len(runs) < 2 {
+        m.appendOutput(m.currentTab, fmt.Sprintf("%s needs to run twice before its runs can be compared\n", name))
+        return
+    }
+    diff := unifiedDiff(outputLines(runs[0]), outputLines(runs[1]))
+    if diff == "" {
+        m.appendOutput(m.currentTab, fmt.Sprintf("The last two runs of %s printed the same output\n", name))
+        return
+    }
+    m.appendOutput(m.currentTab, fmt.Sprintf("Changes in the output of %s since the run before:\n%s", name, diff))
+}
+
+// outputLines splits output into lines without their colors, which would
+// show up as changes.
+func outputLines(output string) []string {
+    output = strings.TrimSuffix(ansiEscape.ReplaceAllString(output, ""), "\n")
+    if output == "" {
+        return nil
+    }
+    return strings.Split(output, "\n")
+}
+
+// unifiedDiff returns the colored changes from a to b in the unified
+// format, or "" when they are the same.
+func unifiedDiff(a, b []string) string {
+    ops := diffLines(a, b)
+    var out strings.Builder
+    for start := 0; start < len(ops); {
+        // Find the next change and take the context around it, along with
+        // any changes close enough to share it
+        first := start
+        for first < len(ops) && ops[first].kind == ' ' {
+            first++
+        }
+        if first == len(ops) {
+            break
+        }
+        from := max(first-diffContext, start)
+        to, kept := first, 0
+        for to < len(ops) && kept <= 2*diffContext {
+            if ops[to].kind == ' ' {
+                kept++
+            } else {
+                kept = 0
+            }
+            to++
+        }
+        to -= max(kept-diffContext, 0)
+
+        // Line numbers in the header count from 1
+        aStart, bStart := 1, 1
+        for _, op := range ops[:from] {
+            if op.kind != '+' {
+                aStart++
+            }
+            if op.kind != '-' {
+                bStart++
+            }
+        }
+        aLen, bLen := 0, 0
+        for _, op := range ops[from:to] {
+            if op.kind != '+' {
+                aLen++
+            }
+            if op.kind != '-' {
+                bLen++
+            }
+        }
+        // Like diff, an empty side is numbered by the line before it
+        if aLen == 0 {
+            aStart--
+        }
+        if bLen == 0 {
+            bStart--
+        }
+        out.WriteString(diffHunk.Render(fmt.Sprintf("@@ -%d,%d +%d,%d @@", aStart, aLen, bStart, bLen)) + "\n")
+        for _, op := range ops[from:to] {
+            line := string(op.kind) + op.line
+            switch op.kind {
+            case '+':
+                line = diffAdded.Render(line)
+            case '-':
+                line = diffRemoved.Render(line)
+            }
+            out.WriteString(line + "\n")
+        }
+        start = to
+    }
+    return out.String()
+}
+
+// diffLines finds the shortest way to turn a into b with Myers' algorithm.
+func diffLines(a, b []string) []diffOp {
+    n, m := len(a), len(b)
+    offset := n + m + 1
+    v := make([]int, 2*offset+1)
+    // Each step only needs the diagonals it can reach, -d-1 to d+1
+    var trace [][]int
+
+search:
+    for d := 0; ; d++ {
+        if d > maxDiffEdits {
+            return replaceLines(a, b)
+        }
+        trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+        for k := -d; k <= d; k += 2 {
+            var x int
+            if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+                x = v[offset+k+1]
+            } else {
+                x = v[offset+k-1] + 1
+            }
+            y := x - k
+            for x < n && y < m && a[x] == b[y] {
+                x, y = x+1, y+1
+            }
+            v[offset+k] = x
+            if x >= n && y >= m {
+                break search
+            }
+        }
+    }
+
+    // Walk back from the end through the furthest points reached
+    var ops []diffOp
+    x, y := n, m
+    for d := len(trace) - 1; d >= 0; d-- {
+        v := trace[d]
+        k := x - y
+        prevK := k - 1
+        if k == -d || (k != d && v[k+d] < v[k+d+2]) {
+            prevK = k + 1
+        }
+        prevX := v[prevK+d+1]
+        prevY := prevX - prevK
+        for x > prevX && y > prevY {
+            ops = append(ops, diffOp{' ', a[x-1]})
+            x, y = x-1, y-1
+        }
+        if d > 0 {
+            if x == prevX {
+                ops = append(ops, diffOp{'+', b[y-1]})
+            } else {
+                ops = append(ops, diffOp{'-', a[x-1]})
+            }
+        }
+        x, y = prevX, prevY
+    }
+    for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+        ops[i], ops[j] = ops[j], ops[i]
+    }
+    return ops
+}
+
+// replaceLines is the diff that removes all of a and adds all of b.
+func replaceLines(a, b []string) []diffOp {
+    ops := make([]diffOp, 0, len(a)+len(b))
+    for _, line := range a {
+        ops = append(ops, diffOp{'-', line})
+    }
+    for _, line := range b {
+        ops = append(ops, diffOp{'+', line})
+    }
+    return ops
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+    "os"
+    "strings"
+
+    "github.com/charmbracelet/bubbles/textarea"
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+)
+
+// editorBox frames the multi-line editor in the middle of the screen.
+var editorBox = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(accentColor).Padding(1, 2)
+
+func newEditor() textarea.Model {
+    ta := textarea.New()
+    ta.Placeholder = "Type a command or script..."
+    ta.CharLimit = 0
+    return ta
+}
+
+// openEditor opens the multi-line editor for the input's command, for
+// longer commands and small scripts. Its text is kept when it is closed
+// without running, for the next time.
+func (m *model) openEditor() tea.Cmd {
+    if value := m.input.Value(); value != "" {
+        m.editor.SetValue(value)
+    }
+    m.editing = true
+    m.sizeEditor()
+    return tea.Batch(m.editor.Focus(), m.announce("Editing a command, ctrl+s runs it and esc closes the editor"))
+}
+
+// sizeEditor fits the editor to the screen.
+func (m *model) sizeEditor() {
+    width, height := 80, 12
+    if m.width > 0 {
+        width = min(width, m.width-editorBox.GetHorizontalFrameSize()-2)
+        height = min(height, m.height-editorBox.GetVerticalFrameSize()-4)
+    }
+    m.editor.SetWidth(max(width, 20))
+    m.editor.SetHeight(max(height, 3))
+}
+
+// editorKey handles keys while the editor is open: ctrl+s runs its text
+// with the user's shell and esc closes it. Everything else edits.
+func (m *model) editorKey(msg tea.KeyMsg) tea.Cmd {
+    switch msg.String() {
+    case "esc":
+        m.editing = false
+        m.editor.Blur()
+        return nil
+    case "ctrl+s":
+        script := m.editor.Value()
+        if strings.TrimSpace(script) == "" {
+            return nil
+        }
+        m.editing = false
+        m.editor.Blur()
+        m.editor.Reset()
+        m.resetInput()
+        m.focus = focusList
+        // The shell expands the script's globs itself
+        noGlob := false
+        return m.runCommand(command{name: scriptName(script), cmd: []string{userShell(), "-c", script}, glob: &noGlob})
+    }
+    var cmd tea.Cmd
+    m.editor, cmd = m.editor.Update(msg)
+    return cmd
+}
+
+// scriptName names a run of script after its first line, marking that more
+// follow.
+func scriptName(script string) string {
+    lines := strings.Split(strings.TrimSpace(script), "\n")
+    if len(lines) > 1 {
+        return strings.TrimSpace(lines[0]) + " …"
+    }
+    return lines[0]
+}
+
+// userShell returns the shell scripts from the editor run with: $SHELL, or
+// sh when it isn't set.
+func userShell() string {
+    if shell := os.Getenv("SHELL"); shell != "" {
+        return shell
+    }
+    return "sh"
+}
+
+// editorView renders the editor as a box in the middle of the screen.
+func (m *model) editorView() string {
+    title := activeTab.Render("Command")
+    footer := m.help.Styles.ShortDesc.Render("ctrl+s run with " + userShell() + " • esc close")
+    box := editorBox.Render(lipgloss.JoinVertical(lipgloss.Left, title, "", m.editor.View(), "", footer))
+    if m.width == 0 {
+        return box
+    }
+    return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
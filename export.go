@@ -0,0 +1,244 @@
+package main
+
+import (
+    "fmt"
+    "html"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// startExport asks for the file to save the current tab's output to, as
+// HTML with its colors or, for a .md file, as a markdown code block.
+func (m *model) startExport() {
+    m.resetInput()
+    m.exporting = true
+    m.input.SetValue(fmt.Sprintf("cmdtui-%s.html", time.Now().Format("20060102-150405")))
+    m.input.CursorEnd()
+    m.input.Placeholder = "File to export the output to, .html or .md..."
+    m.input.Focus()
+    m.focus = focusInput
+}
+
+// export writes the current tab's output to path in the format its
+// extension asks for.
+func (m *model) export(path string) {
+    t := m.tabs[m.currentTab]
+    output, err := t.allOutput()
+    if err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error exporting output: %v\n", err))
+        return
+    }
+    var doc string
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".md", ".markdown":
+        doc = exportMarkdown(output)
+    default:
+        doc = exportHTML(t.title, output)
+    }
+    if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error exporting output: %v\n", err))
+        return
+    }
+    m.appendOutput(m.currentTab, fmt.Sprintf("Exported output to %s\n", path))
+}
+
+// exportMarkdown returns output as a markdown code block, without colors.
+func exportMarkdown(output string) string {
+    plain := ansiEscape.ReplaceAllString(output, "")
+    fence := "```"
+    for strings.Contains(plain, fence) {
+        fence += "`"
+    }
+    if plain != "" && !strings.HasSuffix(plain, "\n") {
+        plain += "\n"
+    }
+    return fence + "text\n" + plain + fence + "\n"
+}
+
+// exportHTML returns output as a standalone HTML page that shows it the way
+// a dark terminal would.
+func exportHTML(title, output string) string {
+    var b strings.Builder
+    b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+    fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+    b.WriteString("<style>body { background: #1c1c1c; color: #d0d0d0; margin: 0; } " +
+        "pre { font-family: ui-monospace, Menlo, Consolas, monospace; padding: 1em; margin: 0; white-space: pre-wrap; }</style>\n")
+    b.WriteString("</head>\n<body>\n<pre>")
+    b.WriteString(ansiToHTML(output))
+    b.WriteString("</pre>\n</body>\n</html>\n")
+    return b.String()
+}
+
+// sgrState is the text style set by the SGR escape sequences seen so far.
+type sgrState struct {
+    fg, bg    string // CSS colors, "" for the default
+    bold      bool
+    faint     bool
+    italic    bool
+    underline bool
+    reverse   bool
+}
+
+// css returns the inline style for text in the state, "" for plain text.
+func (s sgrState) css() string {
+    fg, bg := s.fg, s.bg
+    if s.reverse {
+        fg, bg = bg, fg
+        if fg == "" {
+            fg = "#1c1c1c"
+        }
+        if bg == "" {
+            bg = "#d0d0d0"
+        }
+    }
+    var rules []string
+    if fg != "" {
+        rules = append(rules, "color:"+fg)
+    }
+    if bg != "" {
+        rules = append(rules, "background:"+bg)
+    }
+    if s.bold {
+        rules = append(rules, "font-weight:bold")
+    }
+    if s.faint {
+        rules = append(rules, "opacity:0.6")
+    }
+    if s.italic {
+        rules = append(rules, "font-style:italic")
+    }
+    if s.underline {
+        rules = append(rules, "text-decoration:underline")
+    }
+    return strings.Join(rules, ";")
+}
+
+// apply updates the state with the parameters of an SGR sequence.
+func (s *sgrState) apply(params []int) {
+    if len(params) == 0 {
+        params = []int{0}
+    }
+    for i := 0; i < len(params); i++ {
+        switch p := params[i]; {
+        case p == 0:
+            *s = sgrState{}
+        case p == 1:
+            s.bold = true
+        case p == 2:
+            s.faint = true
+        case p == 3:
+            s.italic = true
+        case p == 4:
+            s.underline = true
+        case p == 7:
+            s.reverse = true
+        case p == 22:
+            s.bold, s.faint = false, false
+        case p == 23:
+            s.italic = false
+        case p == 24:
+            s.underline = false
+        case p == 27:
+            s.reverse = false
+        case p >= 30 && p <= 37:
+            s.fg = xtermColor(p - 30)
+        case p >= 90 && p <= 97:
+            s.fg = xtermColor(p - 90 + 8)
+        case p == 39:
+            s.fg = ""
+        case p >= 40 && p <= 47:
+            s.bg = xtermColor(p - 40)
+        case p >= 100 && p <= 107:
+            s.bg = xtermColor(p - 100 + 8)
+        case p == 49:
+            s.bg = ""
+        case p == 38 || p == 48:
+            // 5;n picks from the 256 colors, 2;r;g;b is a color of its own
+            var color string
+            if i+2 < len(params) && params[i+1] == 5 {
+                color = xtermColor(params[i+2])
+                i += 2
+            } else if i+4 < len(params) && params[i+1] == 2 {
+                color = fmt.Sprintf("#%02x%02x%02x", params[i+2]&255, params[i+3]&255, params[i+4]&255)
+                i += 4
+            } else {
+                return
+            }
+            if p == 38 {
+                s.fg = color
+            } else {
+                s.bg = color
+            }
+        }
+    }
+}
+
+// basicColors are the first 16 of the xterm colors, as xterm shows them.
+var basicColors = [16]string{
+    "#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+    "#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// xtermColor returns the CSS color of one of the 256 xterm colors.
+func xtermColor(n int) string {
+    switch {
+    case n < 0 || n > 255:
+        return ""
+    case n < 16:
+        return basicColors[n]
+    case n < 232:
+        // A 6x6x6 cube
+        n -= 16
+        level := func(v int) int {
+            if v == 0 {
+                return 0
+            }
+            return 55 + v*40
+        }
+        return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+    }
+    gray := 8 + (n-232)*10
+    return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+}
+
+// ansiToHTML turns output into HTML, with its colors and styles as spans.
+// Escape sequences other than colors and styles are left out.
+func ansiToHTML(output string) string {
+    var b strings.Builder
+    var state sgrState
+    text := func(s string) {
+        if s == "" {
+            return
+        }
+        style := state.css()
+        if style != "" {
+            fmt.Fprintf(&b, `<span style="%s">`, style)
+        }
+        b.WriteString(html.EscapeString(s))
+        if style != "" {
+            b.WriteString("</span>")
+        }
+    }
+    last := 0
+    for _, loc := range ansiEscape.FindAllStringIndex(output, -1) {
+        text(output[last:loc[0]])
+        last = loc[1]
+        seq := output[loc[0]:loc[1]]
+        if !strings.HasPrefix(seq, "\x1b[") || !strings.HasSuffix(seq, "m") {
+            continue
+        }
+        var params []int
+        if body := seq[2 : len(seq)-1]; body != "" {
+            for _, field := range strings.Split(strings.ReplaceAll(body, ":", ";"), ";") {
+                n, _ := strconv.Atoi(field)
+                params = append(params, n)
+            }
+        }
+        state.apply(params)
+    }
+    text(output[last:])
+    return b.String()
+}
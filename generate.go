@@ -0,0 +1,186 @@
+package main
+
+import (
+    "fmt"
+    "slices"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+    lua "github.com/yuin/gopher-lua"
+)
+
+// generator makes buttons from the output of a command, such as one per
+// open pull request. Each line of output is handed to the Lua function
+// button, which returns the table for a button like those in the buttons
+// list, or nil to skip the line. The buttons are listed after the shared
+// ones, in the generator's group unless they name their own. Until they are
+// made a stand-in button says they are loading, or for lazy generators,
+// which don't run at startup, loads them when run.
+type generator struct {
+    group    string
+    source   command // The command whose output is read, run like a button
+    button   *lua.LFunction
+    where    string    // Path of the generator in the config, for errors in the buttons it makes
+    commands []command // Made from the last output
+    lazy     bool      // Wait for the stand-in button to be run before making the buttons
+    running  bool      // The command is running
+    loaded   bool      // commands is set, the command finished at least once
+}
+
+// generatedMsg carries the output of a generator's command.
+type generatedMsg struct {
+    generator *generator
+    lines     []string
+    err       error
+}
+
+// extractGenerators reads the generators list. Only Lua configs can have
+// generators, since turning lines into buttons takes a function.
+func extractGenerators(node configNode) []*generator {
+    var generators []*generator
+    for _, item := range node.items() {
+        if !item.table() {
+            if !item.isSet() {
+                item.fail("expected a table, got nothing")
+            }
+            continue
+        }
+        cmdNode := item.key("cmd")
+        g := &generator{
+            group: item.key("group").str(""),
+            source: command{
+                cmd: cmdNode.strs(),
+                dir: item.key("cwd").str(""),
+                env: extractEnv(item.key("env")),
+            },
+            button: item.key("button").function(),
+            where:  item.pathString(),
+            lazy:   item.key("lazy").boolean(false),
+        }
+        g.source.name = g.name()
+        if len(g.source.cmd) == 0 {
+            cmdNode.fail("must contain at least the program to run")
+            continue
+        }
+        if g.button == nil {
+            if !item.key("button").isSet() {
+                item.key("button").fail("missing required value")
+            }
+            continue
+        }
+        generators = append(generators, g)
+    }
+    return generators
+}
+
+// name returns what the generator is called in messages: its group, or the
+// command when it has none.
+func (g *generator) name() string {
+    if g.group != "" {
+        return g.group
+    }
+    return strings.Join(g.source.cmd, " ")
+}
+
+// run runs the generator's command and returns its lines of output.
+func (g *generator) run() generatedMsg {
+    cmd := g.source.expand()
+    out, err := cmd.prepare(cmd.cmd).Output()
+    if err != nil {
+        return generatedMsg{generator: g, err: err}
+    }
+    var lines []string
+    for _, line := range strings.Split(string(out), "\n") {
+        if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+            lines = append(lines, line)
+        }
+    }
+    return generatedMsg{generator: g, lines: lines}
+}
+
+// generate runs every generator in the background, except lazy ones that
+// haven't made their buttons yet. The buttons they make replace the ones
+// they made before as each finishes.
+func (m *model) generate() tea.Cmd {
+    var cmds []tea.Cmd
+    for _, g := range m.generators {
+        if (g.lazy && !g.loaded) || g.running {
+            continue
+        }
+        cmds = append(cmds, runGenerator(g))
+    }
+    if len(cmds) > 0 {
+        // For the stand-ins to say they are loading
+        cmds = append(cmds, m.sortCommands())
+    }
+    return tea.Batch(cmds...)
+}
+
+// runGenerator returns a command that runs g in the background.
+func runGenerator(g *generator) tea.Cmd {
+    g.running = true
+    return func() tea.Msg {
+        return g.run()
+    }
+}
+
+// standIn returns the button listed in place of the buttons of g while it
+// has made none.
+func (g *generator) standIn() command {
+    cmd := command{name: "⋯ loading buttons", group: g.group, generator: g}
+    if !g.running {
+        cmd.name = "▸ load buttons"
+        cmd.description = "runs " + strings.Join(g.source.cmd, " ")
+    }
+    if g.group == "" {
+        cmd.name += " from " + g.name()
+    }
+    return cmd
+}
+
+// generateNow runs every generator and waits for their buttons, for
+// commands that run buttons without the TUI.
+func (m *model) generateNow() {
+    for _, g := range m.generators {
+        m.applyGenerated(g.run())
+    }
+}
+
+// applyGenerated turns the output of a generator into its buttons. Output
+// of generators dropped by a config reload since is ignored.
+func (m *model) applyGenerated(msg generatedMsg) tea.Cmd {
+    if !slices.Contains(m.generators, msg.generator) || m.script == nil {
+        return nil
+    }
+    msg.generator.running = false
+    if msg.err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error generating buttons for %s: %v\n", msg.generator.name(), msg.err))
+        return m.sortCommands()
+    }
+
+    r := newConfigReader(m.script.file)
+    var commands []command
+    for _, line := range msg.lines {
+        value, err := m.script.generate(msg.generator.button, line)
+        if err != nil {
+            m.appendOutput(m.currentTab, fmt.Sprintf("Error generating buttons for %s: %v\n", msg.generator.name(), err))
+            return m.sortCommands()
+        }
+        if value == lua.LNil {
+            continue
+        }
+        node := configNode{r: r, path: []pathSegment{{key: msg.generator.where + ".button"}}, value: luaToGo(value)}
+        if cmd, ok := extractCommand(node); ok {
+            if cmd.group == "" {
+                cmd.group = msg.generator.group
+            }
+            commands = append(commands, cmd)
+        }
+    }
+    if err := r.err(); err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error generating buttons for %s: %v\n", msg.generator.name(), err))
+        return m.sortCommands()
+    }
+    msg.generator.commands, msg.generator.loaded = commands, true
+    return m.sortCommands()
+}
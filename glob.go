@@ -0,0 +1,64 @@
+package main
+
+import (
+    "path/filepath"
+    "strings"
+)
+
+// resolveGlob settles whether cmd expands globs, following the config when
+// the button doesn't choose.
+func (m *model) resolveGlob(cmd command) command {
+    if cmd.glob == nil {
+        glob := m.glob
+        cmd.glob = &glob
+    }
+    return cmd
+}
+
+// expandsGlobs reports whether cmd runs with its globs expanded. Commands
+// run over ssh or typed into tmux are left to the shell there.
+func (cmd command) expandsGlobs() bool {
+    return cmd.glob != nil && *cmd.glob && cmd.target == "" && cmd.tmuxTarget == ""
+}
+
+// expandGlobs replaces the arguments of argv that are patterns such as
+// *.log with the files they match in dir, in order, since no shell does it
+// for buttons. Like a shell, patterns that match nothing are passed on as
+// they are and * only matches hidden files when the pattern starts with a
+// dot. The program itself is never expanded.
+func expandGlobs(argv []string, dir string) []string {
+    if len(argv) == 0 {
+        return argv
+    }
+    expanded := []string{argv[0]}
+    for _, arg := range argv[1:] {
+        if !strings.ContainsAny(arg, "*?[") {
+            expanded = append(expanded, arg)
+            continue
+        }
+        pattern := arg
+        if dir != "" && !filepath.IsAbs(arg) {
+            pattern = filepath.Join(dir, arg)
+        }
+        matches, err := filepath.Glob(pattern)
+        var files []string
+        for _, match := range matches {
+            if strings.HasPrefix(filepath.Base(match), ".") && !strings.HasPrefix(filepath.Base(arg), ".") {
+                continue
+            }
+            if dir != "" && !filepath.IsAbs(arg) {
+                // Keep the paths relative, as they were typed
+                if rel, err := filepath.Rel(dir, match); err == nil {
+                    match = rel
+                }
+            }
+            files = append(files, match)
+        }
+        if err != nil || len(files) == 0 {
+            expanded = append(expanded, arg)
+            continue
+        }
+        expanded = append(expanded, files...)
+    }
+    return expanded
+}
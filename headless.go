@@ -0,0 +1,267 @@
+package main
+
+import (
+    "bufio"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strings"
+    "syscall"
+
+    "github.com/creack/pty"
+)
+
+// runHeadless implements `cmdtui run <button> [argument]`: it runs a single
+// button without the TUI, printing its output to stdout, and returns the
+// exit code to leave with. Buttons that prompt take their argument from the
+// rest of the command line, or read it from stdin. Lua hooks are not called.
+func runHeadless(cfg config, args []string) (int, error) {
+    if len(args) == 0 {
+        return 0, errors.New("usage: cmdtui run <button> [argument]")
+    }
+    m := initialModel(cfg)
+    m.generateNow()
+    cmd, ok := m.findButton(args[0])
+    if !ok {
+        return 0, fmt.Errorf("no button named %q", args[0])
+    }
+    in := bufio.NewReader(os.Stdin)
+    if err := headlessConfirm(cmd, in); err != nil {
+        return 0, err
+    }
+    argv, err := m.headlessArgument(cmd, args[1:], in)
+    if err != nil {
+        return 0, err
+    }
+    return m.runHeadlessButton(cmd, argv...)
+}
+
+// runBatch implements `cmdtui batch [--keep-going] <button>...`: it runs the
+// named buttons one after the other without the TUI, with a separator line
+// before each, and stops at the first one that fails unless --keep-going is
+// given. The exit code is that of the first failure.
+func runBatch(cfg config, args []string) (int, error) {
+    fs := flag.NewFlagSet("batch", flag.ExitOnError)
+    keepGoing := fs.Bool("keep-going", false, "run the remaining buttons after one fails")
+    // Flags may come before, between or after the button names
+    var names []string
+    for fs.Parse(args); fs.NArg() > 0; fs.Parse(args) {
+        names = append(names, fs.Arg(0))
+        args = fs.Args()[1:]
+    }
+    if len(names) == 0 {
+        return 0, errors.New("usage: cmdtui batch [--keep-going] <button>...")
+    }
+
+    m := initialModel(cfg)
+    m.generateNow()
+    cmds := make([]command, len(names))
+    for i, name := range names {
+        cmd, ok := m.findButton(name)
+        if !ok {
+            return 0, fmt.Errorf("no button named %q", name)
+        }
+        cmds[i] = cmd
+    }
+
+    in := bufio.NewReader(os.Stdin)
+    failed := 0
+    for _, cmd := range cmds {
+        fmt.Printf("==> %s\n", cmd.name)
+        err := headlessConfirm(cmd, in)
+        var argv []string
+        if err == nil {
+            argv, err = m.headlessArgument(cmd, nil, in)
+        }
+        code := 1
+        if err == nil {
+            code, err = m.runHeadlessButton(cmd, argv...)
+        }
+        switch {
+        case err != nil:
+            // The command never ran to completion, which is a failure too
+            code = max(code, 1)
+            fmt.Printf("==> %s failed: %v\n", cmd.name, err)
+        case code != 0:
+            fmt.Printf("==> %s failed with exit code %d\n", cmd.name, code)
+        default:
+            continue
+        }
+        if failed == 0 {
+            failed = code
+        }
+        if !*keepGoing {
+            break
+        }
+    }
+    return failed, nil
+}
+
+// headlessArgument returns the prompted argument for cmd, taken from extra
+// or read from in, checked the same way as in the TUI.
+func (m *model) headlessArgument(cmd command, extra []string, in *bufio.Reader) ([]string, error) {
+    if !cmd.prompt {
+        if len(extra) > 0 {
+            return nil, fmt.Errorf("%s takes no argument", cmd.name)
+        }
+        return nil, nil
+    }
+
+    if len(cmd.promptSpec.choices) > 0 {
+        return m.headlessChoices(cmd, extra)
+    }
+
+    value := strings.Join(extra, " ")
+    if len(extra) == 0 {
+        label := cmd.promptSpec.label
+        if label == "" {
+            label = fmt.Sprintf("Argument for %s", cmd.name)
+        }
+        if start := cmd.promptSpec.start(); start != "" {
+            label += fmt.Sprintf(" [%s]", start)
+        }
+        fmt.Fprintf(os.Stderr, "%s: ", label)
+        line, err := in.ReadString('\n')
+        if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+            return nil, fmt.Errorf("reading argument for %s: %v", cmd.name, err)
+        }
+        value = strings.TrimRight(line, "\r\n")
+        if value == "" {
+            value = cmd.promptSpec.start()
+        }
+    }
+    if value == "" {
+        return nil, fmt.Errorf("%s needs an argument", cmd.name)
+    }
+    if problem := m.checkArgument(cmd.promptSpec, value); problem != "" {
+        return nil, fmt.Errorf("invalid argument for %s: %s", cmd.name, problem)
+    }
+    return cmd.promptSpec.args(value), nil
+}
+
+// headlessChoices returns the arguments for the choices of cmd given in
+// extra, or picked in the fuzzy finder. With multiple choices each word of
+// extra is one.
+func (m *model) headlessChoices(cmd command, extra []string) ([]string, error) {
+    values := []string{strings.Join(extra, " ")}
+    if cmd.promptSpec.multiple {
+        values = extra
+    }
+    if len(extra) == 0 {
+        picker := choicePicker(cmd)
+        if err := picker.Run(); err != nil {
+            return nil, fmt.Errorf("picking the argument for %s: %v", cmd.name, err)
+        }
+        values = picker.values
+    }
+    for _, value := range values {
+        if problem := m.checkArgument(cmd.promptSpec, value); problem != "" {
+            return nil, fmt.Errorf("invalid argument for %s: %s", cmd.name, problem)
+        }
+    }
+    return cmd.promptSpec.choiceArgs(values), nil
+}
+
+// runHeadlessButton runs a button like runButton does, but waits for it and
+// prints its output to stdout instead of a tab. Interactive buttons get the
+// terminal directly, as do buttons run with sudo so it can ask for the
+// password. The error is only set when the command could not run
+// to completion at all.
+func (m *model) runHeadlessButton(cmd command, args ...string) (int, error) {
+    if host, ok := m.targets[cmd.target]; ok {
+        cmd.target = host
+    }
+    cmd = cmd.expand()
+    for {
+        p, ok := cmd.nextPlaceholder()
+        if !ok {
+            break
+        }
+        value, kept := m.picked[p.name]
+        if !kept || !p.sticky {
+            picker := &pickerCommand{cmd: cmd, placeholder: p, picked: m.picked}
+            if err := picker.Run(); err != nil {
+                return 0, fmt.Errorf("picking {%s} for %s: %v", p.name, cmd.name, err)
+            }
+            value = picker.value
+            if p.sticky {
+                m.picked[p.name] = value
+            }
+        }
+        cmd = cmd.fill(p.name, value)
+    }
+    cmd.cmd = append(cmd.cmd, args...)
+    if cmd.tmuxTarget == "" {
+        cmd.tmuxTarget = m.tmuxTarget
+    }
+    cmd = m.resolveGlob(cmd)
+
+    if (cmd.interactive || cmd.sudo) && cmd.tmuxTarget == "" {
+        cmd.interactive = true
+        remote := cmd.elevate().remote()
+        c := remote.prepare(remote.cmd)
+        c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+        closeStdin, err := cmd.stdin.feed(c)
+        if err != nil {
+            return 0, fmt.Errorf("reading the stdin of %s: %v", cmd.name, err)
+        }
+        defer closeStdin()
+        return headlessExit(c.Run())
+    }
+
+    j := &job{cmd: cmd.elevate().remote().tmux()}
+    if size, err := pty.GetsizeFull(os.Stdout); err == nil {
+        j.size = *size
+    } else {
+        j.size = pty.Winsize{Rows: 24, Cols: 80}
+    }
+    j.start(j.cmd.cmd)
+
+    // The command has a process group of its own, out of reach of the ^C
+    // the terminal sends to cmdtui, so pass it on
+    signals := make(chan os.Signal, 1)
+    signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+    defer func() {
+        signal.Stop(signals)
+        close(signals)
+    }()
+    go func() {
+        for range signals {
+            j.stop(false)
+        }
+    }()
+
+    var err error
+    for msg := range j.events {
+        switch msg := msg.(type) {
+        case commandOutputMsg:
+            for _, line := range msg.lines {
+                if cmd.capturesOutput() {
+                    j.record(line, msg.stderr)
+                } else if msg.stderr {
+                    fmt.Fprintln(os.Stderr, cmd.renderLine(line))
+                } else {
+                    fmt.Println(cmd.renderLine(line))
+                }
+            }
+        case commandFinishedMsg:
+            err = msg.err
+        }
+    }
+    if cmd.capturesOutput() {
+        fmt.Print(m.renderOutput(cmd, j.captured.String(), int(j.size.Cols)))
+    }
+    return headlessExit(err)
+}
+
+// headlessExit turns the error a command finished with into an exit code,
+// passing on errors for commands that never ran or were killed.
+func headlessExit(err error) (int, error) {
+    if code := exitCode(err); code >= 0 {
+        return code, nil
+    }
+    return 0, err
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "fmt"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+)
+
+// runResult is how the last run of a button ended.
+type runResult struct {
+    ExitCode int       `json:"exit_code"` // -1 if the command never started
+    Finished time.Time `json:"finished"`
+    Seconds  float64   `json:"seconds"`
+}
+
+// jobStatus describes a running job.
+type jobStatus struct {
+    ID      int       `json:"id"`
+    Name    string    `json:"name"`
+    Tab     string    `json:"tab"`
+    Target  string    `json:"target,omitempty"`
+    Started time.Time `json:"started"`
+}
+
+// statusReport is what the status request returns.
+type statusReport struct {
+    Running []jobStatus          `json:"running"`
+    Last    map[string]runResult `json:"last"` // By button name
+    Buttons []string             `json:"buttons"`
+}
+
+func (m *model) status() statusReport {
+    report := statusReport{Running: []jobStatus{}, Last: m.lastRuns, Buttons: []string{}}
+    for _, j := range m.jobs {
+        tab := ""
+        if j.tab < len(m.tabs) {
+            tab = m.tabs[j.tab].title
+        }
+        report.Running = append(report.Running, jobStatus{
+            ID:      j.id,
+            Name:    j.cmd.name,
+            Tab:     tab,
+            Target:  j.cmd.target,
+            Started: j.started,
+        })
+    }
+    for _, cmd := range m.commands {
+        report.Buttons = append(report.Buttons, cmd.name)
+    }
+    return report
+}
+
+// recordRun remembers how the last run of the button called name ended.
+func (m *model) recordRun(name string, err error, elapsed time.Duration) {
+    m.lastRuns[name] = runResult{ExitCode: exitCode(err), Finished: time.Now(), Seconds: elapsed.Seconds()}
+}
+
+// listenHTTP starts serving the control requests over HTTP on addr:
+//
+//    GET  /status        running jobs and last exit codes as JSON
+//    POST /run/{button}  run a button
+//
+// Every request needs the header "Authorization: Bearer <token>", with the
+// config's http_token or else one made up now and written to the file
+// tokenPath returns. So that web pages open in a browser can't use it,
+// requests from a page, which carry an Origin, and ones for a host other
+// than localhost or the one in addr are turned away. An address without a
+// host only listens on localhost.
+func (s *controlServer) listenHTTP(addr, token string) error {
+    if strings.HasPrefix(addr, ":") {
+        addr = "127.0.0.1" + addr
+    }
+    if token == "" {
+        var err error
+        if token, err = s.makeToken(); err != nil {
+            return fmt.Errorf("making the HTTP token: %v", err)
+        }
+    }
+    hosts := map[string]bool{"localhost": true, "127.0.0.1": true, "::1": true}
+    if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+        hosts[host] = true
+    }
+    listener, err := net.Listen("tcp", addr)
+    if err != nil {
+        return err
+    }
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+        reply, err := s.request("status")
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusServiceUnavailable)
+            return
+        }
+        if !strings.HasPrefix(reply, "ok ") {
+            http.Error(w, reply, http.StatusInternalServerError)
+            return
+        }
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprintln(w, strings.TrimPrefix(reply, "ok "))
+    })
+    mux.HandleFunc("POST /run/{button}", func(w http.ResponseWriter, r *http.Request) {
+        reply, err := s.request("run " + r.PathValue("button"))
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusServiceUnavailable)
+            return
+        }
+        if reply != "ok" {
+            http.Error(w, reply, http.StatusNotFound)
+            return
+        }
+        fmt.Fprintln(w, reply)
+    })
+
+    server := &http.Server{
+        Handler:           guardHTTP(mux, token, hosts),
+        ReadHeaderTimeout: 5 * time.Second,
+        ReadTimeout:       10 * time.Second,
+        WriteTimeout:      30 * time.Second,
+        IdleTimeout:       time.Minute,
+    }
+    s.listeners = append(s.listeners, listener)
+    go server.Serve(listener)
+    return nil
+}
+
+// guardHTTP passes on the requests to next that have the token and come
+// from neither a web page nor a name rebound to this machine.
+func guardHTTP(next http.Handler, token string, hosts map[string]bool) http.Handler {
+    want := []byte("Bearer " + token)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        host := r.Host
+        if h, _, err := net.SplitHostPort(host); err == nil {
+            host = h
+        }
+        if !hosts[strings.Trim(host, "[]")] || r.Header.Get("Origin") != "" {
+            http.Error(w, "forbidden", http.StatusForbidden)
+            return
+        }
+        if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+            http.Error(w, "missing or wrong token", http.StatusUnauthorized)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// tokenPath returns where a made up HTTP token is written: http-token next
+// to the state file, readable only by the user.
+func tokenPath() (string, error) {
+    path, err := statePath()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(filepath.Dir(path), "http-token"), nil
+}
+
+// makeToken makes up a token for this session and writes it where
+// tokenPath says, for scripts to read. It is removed on exit.
+func (s *controlServer) makeToken() (string, error) {
+    secret := make([]byte, 32)
+    if _, err := rand.Read(secret); err != nil {
+        return "", err
+    }
+    token := hex.EncodeToString(secret)
+    path, err := tokenPath()
+    if err != nil {
+        return "", err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+        return "", err
+    }
+    if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+        return "", err
+    }
+    s.tokenFile = path
+    return token, nil
+}
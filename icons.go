@@ -0,0 +1,93 @@
+package main
+
+import (
+    "os"
+    "strings"
+    "time"
+    "unicode"
+
+    "github.com/charmbracelet/bubbles/spinner"
+)
+
+// glyphs is whether the terminal can be expected to draw more than ASCII,
+// going by its locale. The Linux console can't draw emoji whatever the
+// locale says.
+var glyphs = supportsGlyphs()
+
+func supportsGlyphs() bool {
+    if os.Getenv("TERM") == "linux" {
+        return false
+    }
+    // The first of these that is set decides, as for any program
+    for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+        if locale := strings.ToLower(os.Getenv(name)); locale != "" {
+            return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+        }
+    }
+    return false
+}
+
+// shownIcon returns what goes before the button's name: its icon, or where
+// the terminal can't draw that its icon_fallback, which may be nothing.
+func (cmd command) shownIcon() string {
+    if glyphs || isASCII(cmd.icon) {
+        return cmd.icon
+    }
+    return cmd.plainIcon
+}
+
+func isASCII(s string) bool {
+    return strings.IndexFunc(s, func(r rune) bool { return r > unicode.MaxASCII }) < 0
+}
+
+// withIcon puts icon before title.
+func withIcon(icon, title string) string {
+    if icon == "" {
+        return title
+    }
+    return icon + " " + title
+}
+
+// iconSet is what the status of commands is shown with.
+type iconSet struct {
+    run     string          // Starts the header of each run in a tab's output
+    success string          // Starts the line a run that succeeded ends with
+    failure string          // Starts the line a run that failed ends with
+    spinner spinner.Spinner // Turns while commands run
+}
+
+// iconSets are the sets the config's icons setting can pick. Nerd Fonts
+// patch icons into the private use area of ordinary fonts.
+var iconSets = map[string]iconSet{
+    "unicode": {run: "▶", success: "✔", failure: "✘", spinner: spinner.Dot},
+    "nerd": {
+        run:     "\uf04b", // nf-fa-play
+        success: "\uf00c", // nf-fa-check
+        failure: "\uf00d", // nf-fa-times
+        // nf-fa-hourglass_start, _half and _end
+        spinner: spinner.Spinner{Frames: []string{"\uf251 ", "\uf252 ", "\uf253 "}, FPS: time.Second / 3},
+    },
+    "ascii": {run: "==>", success: "+", failure: "x", spinner: spinner.Line},
+}
+
+// icons is the set in use.
+var icons = iconSets["unicode"]
+
+// extractIcons reads the icons setting: unicode, nerd, ascii or auto, the
+// default, for unicode where the terminal can draw it and ascii where it
+// can't. Whether the font has Nerd Font icons can't be told from here, so
+// they have to be asked for.
+func extractIcons(n configNode) iconSet {
+    name := n.str("auto")
+    if name == "auto" {
+        if glyphs {
+            return iconSets["unicode"]
+        }
+        return iconSets["ascii"]
+    }
+    set, ok := iconSets[name]
+    if !ok {
+        n.fail("expected auto, unicode, nerd or ascii, got %q", name)
+    }
+    return set
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+
+    "gopkg.in/yaml.v3"
+)
+
+// importer reads the tasks defined for another tool as buttons.
+type importer struct {
+    files []string // Looked for in the working directory when the import is just true, the first found is used
+    group string   // Shown before the names of the buttons it adds, unless read sets one
+    read  func(path string) ([]command, error)
+}
+
+// importers are the tools whose tasks can be imported, by the name used in
+// the import section.
+var importers = map[string]importer{
+    "makefile": {files: []string{"Makefile"}, group: "make", read: importMakefile},
+    "npm":      {files: []string{"package.json"}, read: importPackageJSON},
+    "just":     {files: []string{"justfile", "Justfile", ".justfile"}, group: "just", read: importJustfile},
+    "task":     {files: []string{"Taskfile.yml", "taskfile.yml", "Taskfile.yaml", "taskfile.yaml"}, group: "task", read: importTaskfile},
+}
+
+// importButtons adds buttons for the tasks in the files named by the import
+// section, e.g. import = { makefile = true }. True looks for the tool's usual
+// files in the working directory, a string names the file. Missing files are
+// skipped. Imported files are watched like included ones, so the buttons
+// follow changes to them. The files are read at the same time, since none
+// depends on another, and their buttons added in the order of their names.
+func (cfg *config) importButtons(node configNode) {
+    type pending struct {
+        item     configNode
+        imp      importer
+        path     string
+        commands []command
+        err      error
+    }
+    var imports []*pending
+
+    names := node.keys()
+    sort.Strings(names)
+    for _, name := range names {
+        item := node.key(name)
+        imp, ok := importers[name]
+        if !ok {
+            known := make([]string, 0, len(importers))
+            for n := range importers {
+                known = append(known, n)
+            }
+            sort.Strings(known)
+            item.fail("unknown import, expected one of %s", strings.Join(known, ", "))
+            continue
+        }
+
+        path := imp.files[0]
+        if _, isBool := item.value.(bool); isBool {
+            if !item.boolean(false) {
+                continue
+            }
+            for _, file := range imp.files {
+                if _, err := os.Stat(file); err == nil {
+                    path = file
+                    break
+                }
+            }
+        } else if path = item.requiredStr(); path == "" {
+            continue
+        }
+        if abs, err := filepath.Abs(path); err == nil {
+            path = abs
+        }
+        cfg.files = append(cfg.files, path)
+        imports = append(imports, &pending{item: item, imp: imp, path: path})
+    }
+
+    var read sync.WaitGroup
+    for _, p := range imports {
+        read.Add(1)
+        go func() {
+            defer read.Done()
+            p.commands, p.err = p.imp.read(p.path)
+        }()
+    }
+    read.Wait()
+
+    for _, p := range imports {
+        if errors.Is(p.err, fs.ErrNotExist) {
+            continue
+        } else if p.err != nil {
+            p.item.fail("%v", p.err)
+            continue
+        }
+        for i := range p.commands {
+            if p.commands[i].group == "" {
+                p.commands[i].group = p.imp.group
+            }
+        }
+        cfg.commands = append(cfg.commands, p.commands...)
+    }
+}
+
+// taskCommand returns the button for a task run by argv from the directory
+// holding path.
+func taskCommand(path, name string, argv ...string) command {
+    return command{name: name, cmd: argv, dir: filepath.Dir(path)}
+}
+
+// makeTarget matches a rule in a Makefile and captures its targets. Pattern
+// rules, targets built from variables, special targets such as .PHONY and
+// variable assignments don't match.
+var makeTarget = regexp.MustCompile(`^([^\s:#=$%.][^:#=$%]*?)\s*::?(?:[^=]|$)`)
+
+// importMakefile adds a button for each target in the Makefile at path.
+func importMakefile(path string) ([]command, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var commands []command
+    seen := make(map[string]bool)
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        match := makeTarget.FindStringSubmatch(scanner.Text())
+        if match == nil {
+            continue
+        }
+        for _, target := range strings.Fields(match[1]) {
+            if seen[target] {
+                continue
+            }
+            seen[target] = true
+            argv := []string{"make", target}
+            if base := filepath.Base(path); base != "Makefile" {
+                argv = []string{"make", "-f", base, target}
+            }
+            commands = append(commands, taskCommand(path, target, argv...))
+        }
+    }
+    return commands, scanner.Err()
+}
+
+// importPackageJSON adds a button for each script in the package.json at
+// path, in the order they are listed, run by the package manager the
+// project uses.
+func importPackageJSON(path string) ([]command, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    var pkg struct {
+        Scripts        json.RawMessage `json:"scripts"`
+        PackageManager string          `json:"packageManager"`
+    }
+    if err := json.Unmarshal(data, &pkg); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+    if len(pkg.Scripts) == 0 {
+        return nil, nil
+    }
+
+    // Walk the tokens rather than decoding into a map to keep the order
+    dec := json.NewDecoder(strings.NewReader(string(pkg.Scripts)))
+    if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+        return nil, fmt.Errorf("%s: scripts must be an object", path)
+    }
+    runner := packageManager(path, pkg.PackageManager)
+    var commands []command
+    for dec.More() {
+        tok, err := dec.Token()
+        if err != nil {
+            return nil, fmt.Errorf("%s: %v", path, err)
+        }
+        name := tok.(string)
+        var script string
+        if err := dec.Decode(&script); err != nil {
+            return nil, fmt.Errorf("%s: scripts.%s: %v", path, name, err)
+        }
+        cmd := taskCommand(path, name, runner, "run", name)
+        cmd.group = runner
+        commands = append(commands, cmd)
+    }
+    return commands, nil
+}
+
+// packageLockFiles tell which package manager a project uses.
+var packageLockFiles = []struct {
+    file   string
+    runner string
+}{
+    {"pnpm-lock.yaml", "pnpm"},
+    {"yarn.lock", "yarn"},
+    {"bun.lockb", "bun"},
+    {"bun.lock", "bun"},
+}
+
+// packageManager returns the package manager for the package.json at path:
+// the one named by its packageManager field, such as "yarn@4.1.0", or the
+// one whose lock file is next to it, or npm.
+func packageManager(path, field string) string {
+    if name, _, _ := strings.Cut(field, "@"); name != "" {
+        return name
+    }
+    for _, lock := range packageLockFiles {
+        if _, err := os.Stat(filepath.Join(filepath.Dir(path), lock.file)); err == nil {
+            return lock.runner
+        }
+    }
+    return "npm"
+}
+
+var (
+    // justRecipe matches the first line of a recipe in a justfile and
+    // captures its name and parameters. Assignments, aliases and settings
+    // use := and don't match.
+    justRecipe = regexp.MustCompile(`^@?([A-Za-z_][\w-]*)((?:\s+[^:]*?)?)\s*:(?:[^=]|$)`)
+    // justParam matches one recipe parameter and captures whether it is
+    // variadic, its name and its default.
+    justParam = regexp.MustCompile(`([+*]?)\$?([A-Za-z_][\w-]*)(?:=('[^']*'|"[^"]*"|\([^)]*\)|\S+))?`)
+    // justDoc matches the doc attribute, which overrides the comment above
+    // a recipe.
+    justDoc = regexp.MustCompile(`doc\(\s*(?:'([^']*)'|"([^"]*)")\s*\)`)
+)
+
+// importJustfile adds a button for each public recipe in the justfile at
+// path, described by the comment above it. Recipes with parameters that
+// have no default prompt for them.
+func importJustfile(path string) ([]command, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    var commands []command
+    var comment, doc string
+    private := false
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        line := scanner.Text()
+        trimmed := strings.TrimSpace(line)
+        switch {
+        case strings.HasPrefix(trimmed, "#") && line == trimmed:
+            if !strings.HasPrefix(trimmed, "#!") {
+                comment = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+            }
+            continue
+        case strings.HasPrefix(line, "["):
+            // Attributes such as [private] or [doc('...')] apply to the
+            // recipe below
+            private = private || strings.Contains(justDoc.ReplaceAllString(line, ""), "private")
+            if match := justDoc.FindStringSubmatch(line); match != nil {
+                doc = match[1] + match[2]
+            }
+            continue
+        }
+
+        match := justRecipe.FindStringSubmatch(line)
+        if match != nil && !private && !strings.HasPrefix(match[1], "_") {
+            if doc != "" {
+                comment = doc
+            }
+            argv := []string{"just", match[1]}
+            if base := filepath.Base(path); base != "justfile" && base != "Justfile" && base != ".justfile" {
+                argv = []string{"just", "-f", base, match[1]}
+            }
+            cmd := taskCommand(path, match[1], argv...)
+            cmd.description = comment
+            cmd.prompt, cmd.promptSpec = justPrompt(match[2])
+            commands = append(commands, cmd)
+        }
+        if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+            comment, doc, private = "", "", false
+        }
+    }
+    return commands, scanner.Err()
+}
+
+// justPrompt returns the prompt for a recipe taking params. It only asks
+// when a parameter has no default, and the label lists the parameters with
+// the optional ones in brackets.
+func justPrompt(params string) (bool, promptSpec) {
+    var spec promptSpec
+    var names []string
+    required := false
+    for _, match := range justParam.FindAllStringSubmatch(params, -1) {
+        name := match[2]
+        spec.params = append(spec.params, name)
+        if match[1] != "" {
+            name += "..."
+        }
+        if match[3] != "" || match[1] == "*" {
+            name = "[" + name + "]"
+        } else {
+            required = true
+        }
+        names = append(names, name)
+    }
+    if !required {
+        return false, promptSpec{}
+    }
+    spec.label = strings.Join(names, " ")
+    return true, spec
+}
+
+// importTaskfile adds a button for each task in the Taskfile at path, in the
+// order they are defined, described by their desc. Internal tasks are left
+// out. Tasks that require variables prompt for them, and they are passed as
+// NAME=value.
+func importTaskfile(path string) ([]command, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+    // Decode into nodes rather than a map to keep the order
+    var root yaml.Node
+    if err := yaml.Unmarshal(data, &root); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+    if len(root.Content) == 0 {
+        return nil, nil
+    }
+    tasks := yamlKey(root.Content[0], "tasks")
+    if tasks == nil {
+        return nil, nil
+    }
+    if tasks.Kind != yaml.MappingNode {
+        return nil, fmt.Errorf("%s: tasks must be a mapping", path)
+    }
+
+    var commands []command
+    for i := 0; i+1 < len(tasks.Content); i += 2 {
+        name, task := tasks.Content[i].Value, tasks.Content[i+1]
+        argv := []string{"task", name}
+        if base := filepath.Base(path); base != "Taskfile.yml" && base != "taskfile.yml" && base != "Taskfile.yaml" && base != "taskfile.yaml" {
+            argv = []string{"task", "-t", base, name}
+        }
+        cmd := taskCommand(path, name, argv...)
+        if task.Kind == yaml.MappingNode {
+            if internal := yamlKey(task, "internal"); internal != nil && internal.Value == "true" {
+                continue
+            }
+            if desc := yamlKey(task, "desc"); desc != nil {
+                cmd.description = desc.Value
+            }
+            if vars := yamlKey(yamlKey(task, "requires"), "vars"); vars != nil {
+                for _, v := range vars.Content {
+                    if v.Kind == yaml.MappingNode {
+                        v = yamlKey(v, "name")
+                    }
+                    if v != nil && v.Value != "" {
+                        cmd.promptSpec.params = append(cmd.promptSpec.params, v.Value)
+                    }
+                }
+            }
+            if len(cmd.promptSpec.params) > 0 {
+                cmd.prompt = true
+                cmd.promptSpec.assign = true
+                cmd.promptSpec.label = strings.Join(cmd.promptSpec.params, " ")
+            }
+        }
+        commands = append(commands, cmd)
+    }
+    return commands, nil
+}
+
+// yamlKey returns the value for key in the mapping node, or nil.
+func yamlKey(node *yaml.Node, key string) *yaml.Node {
+    if node == nil || node.Kind != yaml.MappingNode {
+        return nil
+    }
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        if node.Content[i].Value == key {
+            return node.Content[i+1]
+        }
+    }
+    return nil
+}
+
+// scriptHeader matches a line of metadata in a script's leading comments,
+// e.g. "# cmdtui: description: Deploy the app", and captures the key and
+// value.
+var scriptHeader = regexp.MustCompile(`^(?:#|//|--)\s*cmdtui:\s*(\w+)\s*(?::\s*(.*))?$`)
+
+// scriptButtons adds a button for each executable file in the directories
+// listed in node, named after the file. Paths are relative to the config
+// file. Missing directories are skipped. The directories are watched, so
+// scripts added later show up.
+func (cfg *config) scriptButtons(node configNode, from string) {
+    for _, item := range node.items() {
+        dir := item.str("")
+        if dir == "" {
+            item.fail("must not be empty")
+            continue
+        }
+        if !filepath.IsAbs(dir) {
+            dir = filepath.Join(filepath.Dir(from), dir)
+        }
+        if abs, err := filepath.Abs(dir); err == nil {
+            dir = abs
+        }
+        entries, err := os.ReadDir(dir)
+        if errors.Is(err, fs.ErrNotExist) {
+            continue
+        } else if err != nil {
+            item.fail("%v", err)
+            continue
+        }
+        cfg.dirs = append(cfg.dirs, dir)
+
+        for _, entry := range entries {
+            path := filepath.Join(dir, entry.Name())
+            // Stat rather than the entry's info to follow symlinks
+            info, err := os.Stat(path)
+            if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 || strings.HasPrefix(entry.Name(), ".") {
+                continue
+            }
+            cmd, err := scriptCommand(path)
+            if err != nil {
+                item.fail("%v", err)
+                continue
+            }
+            cfg.files = append(cfg.files, path)
+            cfg.commands = append(cfg.commands, cmd)
+        }
+    }
+}
+
+// scriptCommand returns the button for the script at path, set up by the
+// "cmdtui:" lines among the comments at the top of the script:
+//
+//    # cmdtui: description: Deploy the app
+//    # cmdtui: prompt: Environment
+//    # cmdtui: group: ops
+//
+// prompt asks for an argument, with the value as the label if there is one.
+func scriptCommand(path string) (command, error) {
+    cmd := command{name: filepath.Base(path), cmd: []string{path}}
+    f, err := os.Open(path)
+    if err != nil {
+        return cmd, err
+    }
+    defer f.Close()
+
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || strings.HasPrefix(line, "#!") {
+            continue
+        }
+        if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "--") {
+            // The header ends with the first line of code
+            break
+        }
+        match := scriptHeader.FindStringSubmatch(line)
+        if match == nil {
+            continue
+        }
+        switch key, value := match[1], strings.TrimSpace(match[2]); key {
+        case "description":
+            cmd.description = value
+        case "prompt":
+            cmd.prompt = true
+            cmd.promptSpec.label = value
+        case "group":
+            cmd.group = value
+        default:
+            return cmd, fmt.Errorf("%s: unknown cmdtui header %q, expected description, prompt or group", path, key)
+        }
+    }
+    // Binary files have no header and can be too long to scan
+    if err := scanner.Err(); err != nil && !errors.Is(err, bufio.ErrTooLong) {
+        return cmd, err
+    }
+    return cmd, nil
+}
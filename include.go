@@ -0,0 +1,139 @@
+package main
+
+import (
+    "path/filepath"
+    "sort"
+)
+
+// includableKeys are the settings an included file may contribute. Layout
+// and alerts belong to the main config alone.
+var includableKeys = map[string]bool{
+    "buttons":     true,
+    "tabs":        true,
+    "completions": true,
+    "profiles":    true,
+    "keys":        true,
+    "aliases":     true,
+    "targets":     true,
+    "include":     true,
+}
+
+// include merges the files listed in node into cfg. Paths are relative to
+// the file doing the including. Buttons are added after the ones already
+// read, new tabs after the existing ones, completion lists are joined and
+// profiles with the same name are combined. Keys, aliases and targets set
+// by the including file win. Lua files share a single state, so hooks and functions from any of
+// them can be used.
+func (cfg *config) include(node configNode, from string) {
+    for _, item := range node.items() {
+        name := item.str("")
+        if name == "" {
+            item.fail("must not be empty")
+            continue
+        }
+        path := name
+        if !filepath.IsAbs(path) {
+            path = filepath.Join(filepath.Dir(from), path)
+        }
+        if cfg.hasFile(path) {
+            item.fail("%s is already included", name)
+            continue
+        }
+        cfg.files = append(cfg.files, path)
+
+        raw, script, err := loadConfigFile(path, cfg.script)
+        cfg.script = script
+        if err != nil {
+            item.fail("%v", err)
+            continue
+        }
+
+        r := newConfigReader(path)
+        root := r.root(raw)
+        cfg.merge(root)
+        cfg.include(root.key("include"), path)
+        // Problems in the included file are reported against that file
+        node.r.errs = append(node.r.errs, r.errs...)
+    }
+}
+
+// hasFile reports whether path was already read, to stop include cycles.
+func (cfg *config) hasFile(path string) bool {
+    abs, _ := filepath.Abs(path)
+    for _, file := range cfg.files {
+        if other, _ := filepath.Abs(file); other == abs {
+            return true
+        }
+    }
+    return false
+}
+
+// merge adds the settings of an included file's root to cfg.
+func (cfg *config) merge(root configNode) {
+    for _, name := range root.keys() {
+        if !includableKeys[name] {
+            root.key(name).fail("can only be set in the main config")
+        }
+    }
+
+    cfg.commands = append(cfg.commands, extractCommands(root.key("buttons"))...)
+
+    for _, t := range extractTabs(root.key("tabs")) {
+        if !cfg.hasTab(t.title) {
+            cfg.tabs = append(cfg.tabs, t)
+        }
+    }
+
+    completions := extractCompletions(root.key("completions"))
+    if completions.fn != nil {
+        if cfg.completions.isSet() {
+            root.key("completions").fail("a completions function cannot be combined with other completions")
+        } else {
+            cfg.completions = completions
+        }
+    } else if completions.list != nil {
+        if cfg.completions.fn != nil {
+            root.key("completions").fail("a completions function cannot be combined with other completions")
+        } else {
+            cfg.completions.list = append(cfg.completions.list, completions.list...)
+        }
+    }
+
+    for _, p := range extractProfiles(root.key("profiles")) {
+        i := findProfile(cfg.profiles, p.name)
+        if i < 0 {
+            cfg.profiles = append(cfg.profiles, p)
+            continue
+        }
+        cfg.profiles[i].commands = append(cfg.profiles[i].commands, p.commands...)
+        if !cfg.profiles[i].completions.isSet() {
+            cfg.profiles[i].completions = p.completions
+        }
+    }
+    sort.Slice(cfg.profiles, func(i, j int) bool { return cfg.profiles[i].name < cfg.profiles[j].name })
+
+    for name, keys := range extractKeys(root.key("keys")) {
+        if _, ok := cfg.keys[name]; !ok {
+            cfg.keys[name] = keys
+        }
+    }
+    for name, argv := range extractAliases(root.key("aliases")) {
+        if _, ok := cfg.aliases[name]; !ok {
+            cfg.aliases[name] = argv
+        }
+    }
+    for name, host := range extractTargets(root.key("targets")) {
+        if _, ok := cfg.targets[name]; !ok {
+            cfg.targets[name] = host
+        }
+    }
+}
+
+func (cfg *config) hasTab(title string) bool {
+    for _, t := range cfg.tabs {
+        if t.title == title {
+            return true
+        }
+    }
+    return false
+}
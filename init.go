@@ -0,0 +1,535 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+// starterConfigs holds the config written by `cmdtui init`, by format.
+var starterConfigs = map[string]string{
+    "lua": `-- cmdtui configuration
+--
+-- The file is plain Lua and must return a table. Anything Lua can do is
+-- available while building it, e.g. computing completions from a command.
+
+return {
+    -- Each button becomes an entry in the list on the left. cmd is the argv
+    -- to execute, no shell is involved unless you run one yourself.
+    buttons = {
+        { name = "Say hello", cmd = {"echo", "hello from cmdtui"} },
+
+        -- description is shown next to the button while it is selected, and
+        -- tags let # narrow the list down to one category
+        { name = "Disk usage", cmd = {"df", "-h"}, description = "Free space on each filesystem", tags = {"system"} },
+
+        -- prompt = true asks for one more argument before running
+        { name = "Search files", cmd = {"grep", "-rn"}, prompt = true },
+
+        -- prompt can also label the input and validate the argument with a
+        -- pattern, or a function returning false or a message when it is bad
+        { name = "Show port", cmd = {"lsof", "-i"}, prompt = { label = "Port", validate = "^:[0-9]+$" } },
+
+        -- type = "number" asks for a number between min and max, starting at
+        -- default, which + and - step up and down
+        { name = "Sleep", cmd = {"sleep"}, prompt = { type = "number", min = 1, max = 60, default = 5 } },
+
+        -- type = "time" starts at now, or default like "-24h" before it, which
+        -- the arrow keys step. format is date, datetime, rfc3339, unix or a Go
+        -- time layout
+        { name = "Journal since", cmd = {"journalctl", "--since"}, prompt = { type = "time", default = "-1h", step = "15m" } },
+
+        -- choices has the argument picked from a list instead of typed
+        { name = "Tail log", cmd = {"tail", "-n", "50"}, prompt = { choices = {"/var/log/syslog", "/var/log/auth.log"} } },
+
+        -- multiple lets several choices be marked with tab, each passed after
+        -- flag, or as one argument joined with join
+        { name = "Restart units", cmd = {"systemctl", "restart"}, prompt = { choices = {"nginx", "postgresql", "redis"}, multiple = true } },
+
+        -- env adds variables to the command's environment
+        { name = "Greet from env", cmd = {"sh", "-c", "echo $GREETING, $USER"}, env = { GREETING = "Hi" } },
+
+        -- pty runs the command on a pseudo-terminal for programs that need
+        -- one. Lines typed into the input box are sent to it while it runs.
+        { name = "Top once", cmd = {"top", "-b", "-n", "1"}, pty = true },
+
+        -- input keeps standard input open, so lines typed with ctrl+t go to
+        -- the command. Otherwise it reads nothing, like from /dev/null
+        -- { name = "Python", cmd = {"python3", "-i"}, input = true },
+
+        -- interactive hands the whole terminal to the command until it exits
+        { name = "Top", cmd = {"top"}, interactive = true },
+
+        -- {container} asks which running container to use before the
+        -- command runs
+        { name = "Container logs", cmd = {"docker", "logs", "--tail", "100", "{container}"} },
+
+        -- {git_branch} and {git_ref} ask for a branch, or any branch or tag,
+        -- of the repository the command runs in
+        { name = "Checkout", cmd = {"git", "checkout", "{git_branch}"} },
+
+        -- {context} and {namespace} are picked from your kubeconfig the
+        -- first time and kept until you press K to switch
+        -- { name = "Pods", cmd = {"kubectl", "--context", "{context}", "-n", "{namespace}", "get", "pods"} },
+
+        -- capture keeps the output in a variable for later commands to use as
+        -- {POD}, with a pattern only its first match or the match's group
+        -- { name = "Newest pod", cmd = {"kubectl", "get", "pods", "-o", "name", "--sort-by=.metadata.creationTimestamp"},
+        --   capture = { name = "POD", pattern = "pod/(\\S+)\\s*$" } },
+        -- { name = "Shell in pod", cmd = {"kubectl", "exec", "-it", "{POD}", "--", "sh"}, interactive = true },
+
+        -- enabled hides the button while it returns false, here unless the
+        -- directory has a compose file
+        { name = "Compose up", cmd = {"docker", "compose", "up", "-d"},
+          enabled = function() return io.open("compose.yaml") ~= nil end },
+
+        -- stdin is given to the command on its standard input, or with
+        -- { file = "deploy.yaml" } the file, relative to cwd
+        -- { name = "Count users", cmd = {"psql", "app"}, stdin = [[
+        --     SELECT count(*) FROM users;
+        -- ]] },
+        -- { name = "Apply manifest", cmd = {"kubectl", "apply", "-f", "-"}, stdin = { file = "deploy.yaml" } },
+
+        -- confirm_phrase has to be typed exactly before the command runs
+        -- { name = "Drop database", cmd = {"dropdb", "app"}, confirm_phrase = "drop app" },
+
+        -- sudo runs the command as root, asking for the password in the input box
+        -- { name = "Restart nginx", cmd = {"systemctl", "restart", "nginx"}, sudo = true },
+
+        -- cwd runs the command in another directory. icon is shown before
+        -- the name, or icon_fallback where the terminal can't draw it
+        { name = "List /tmp", cmd = {"ls", "-la"}, cwd = "/tmp", icon = "📂", icon_fallback = ">" },
+
+        -- $VAR and ${VAR} in cmd and cwd are expanded when the button runs,
+        -- using env first and then the environment cmdtui was started in.
+        -- Variables set in neither are left for the shell.
+        { name = "List home", cmd = {"ls", "$HOME"}, cwd = "~" },
+
+        -- glob = true expands patterns like *.log to the matching files,
+        -- which a shell would otherwise do
+        { name = "Log sizes", cmd = {"du", "-h", "*.log"}, cwd = "/var/log", glob = true },
+
+        -- progress turns matching output into a progress bar, notify sends a
+        -- desktop notification when the command ran for at least 5 seconds
+        { name = "Slow count", cmd = {"sh", "-c", "for i in 1 2 3 4 5; do echo \"$i/5\"; sleep 1; done"},
+          progress = "(\\d+)/(\\d+)", notify = 5 },
+
+        -- watch_paths re-runs the command whenever a file below them changes
+        -- { name = "Test", cmd = {"go", "test", "./..."}, watch_paths = {"./src", "go.mod"} },
+
+        -- render formats the output: raw, ansi, json, table or markdown
+        { name = "Processes", cmd = {"ps", "-eo", "pid,user,%cpu,comm"}, render = "table" },
+
+        -- transform rewrites the whole output once the command exits
+        { name = "Count files", cmd = {"ls", "-1"},
+          transform = function(output)
+              local _, n = output:gsub("\n", "")
+              return n .. " files"
+          end },
+    },
+
+    -- Add the targets of the Makefile, the scripts of the package.json and
+    -- the recipes of the justfile and Taskfile.yml in the working directory
+    -- as buttons. Recipe and task parameters are asked for when they run.
+    -- import = { makefile = true, npm = true, just = true, task = true },
+
+    -- Every executable file in these directories becomes a button named
+    -- after it. Comments like "# cmdtui: description: Deploy the app" and
+    -- "# cmdtui: prompt: Environment" at the top of a script set it up.
+    -- script_dirs = {"./scripts"},
+
+    -- Generators make buttons from the output of a command: button turns
+    -- each line into a button table, or nil to skip it. With lazy = true
+    -- the command waits for its "load buttons" button instead of startup
+    -- generators = {
+    --     { group = "pr", cmd = {"gh", "pr", "list"},
+    --       button = function(line)
+    --           local n, title = line:match("^(%d+)%s+([^\t]*)")
+    --           if n then return { name = n .. " " .. title, cmd = {"gh", "pr", "view", n} } end
+    --       end },
+    -- },
+
+    -- Split large setups into several files. Their buttons, tabs,
+    -- completions, profiles and keys are merged into this config.
+    -- include = {"docker.lua", "k8s.yaml"},
+
+    -- Profiles add their own buttons and completions to the shared ones,
+    -- press P to switch between them
+    -- profile = "dev",
+    -- profiles = {
+    --     dev = { buttons = { { name = "Serve", cmd = {"make", "serve"} } } },
+    --     prod = { buttons = { { name = "Deploy", cmd = {"make", "deploy"} } } },
+    -- },
+
+    -- Output tabs, switch between them with [ and ]. A tab can set its
+    -- layout: the button list "left" or "right" of the output, "hidden",
+    -- or "viewport" to show nothing but the output. wrap = true wraps long
+    -- lines instead of cutting them off, w toggles it.
+    tabs = {"Main", { name = "Logs", layout = "viewport", wrap = true }},
+
+    -- Optional layout, these are the defaults
+    viewport = { width = 110, height = 20 },
+    list = { width = 45, height = 20 },
+    textinput = { width = 107 },
+
+    -- Suggestions cycled with tab in the input box. This can also be a
+    -- function(prefix) returning suggestions for what has been typed, and
+    -- buttons that prompt can have completions of their own.
+    completions = {"git status", "git log --oneline", "make"},
+
+    -- Aliases expand the first word of commands typed into the input box
+    aliases = { k = "kubectl", dc = "docker compose" },
+
+    -- Hosts buttons can run on over ssh with target = "prod". A button's
+    -- target can also be any host ssh understands.
+    -- targets = { prod = "deploy@prod.example.com" },
+
+    -- Type commands into an existing tmux pane instead of running them
+    -- here. Buttons can also set their own tmux_target.
+    -- tmux_target = "work:1.0",
+
+    -- Token requests to --http need as "Authorization: Bearer <token>".
+    -- Without it one is made up per session, see the README
+    -- http_token = "change me",
+
+    -- Expand globs in typed commands and every button that doesn't set
+    -- glob itself
+    -- glob = true,
+
+    -- Megabytes of output each tab keeps in memory. Older output moves to
+    -- a temporary file, which the pager still shows
+    -- tab_memory_mb = 64,
+
+    -- Remap any binding by name
+    -- keys = { quit = {"q", "ctrl+c"} },
+
+    -- high-contrast keeps to black, white and the brightest colors, for
+    -- when the default pinks and purples are hard to tell apart. --theme
+    -- picks one for a single run. styles changes are made on top of it
+    -- theme = "high-contrast",
+
+    -- Change any style by name: colors (one, or { light = ..., dark = ... }
+    -- for either background), bold, italic, underline, faint, reverse,
+    -- padding, margin, border and border_foreground
+    -- styles = {
+    --     active_button = { background = "#005f87", bold = true },
+    --     focused_border = { border = "rounded", border_foreground = { light = "25", dark = "39" } },
+    --     doc = { margin = {0, 1} },
+    -- },
+
+    -- The border of every pane: normal, rounded, double, thick, hidden or
+    -- none. A table sets the list, output and input panes apart.
+    -- borders = { list = "none", output = "rounded", input = "rounded" },
+
+    -- How the status of commands is shown: unicode, nerd for Nerd Font
+    -- icons, ascii, or auto for unicode where the locale allows it
+    -- icons = "nerd",
+
+    bell_on_failure = false,
+    flash_on_failure = true,
+
+    -- Stop the spinner and progress bar moving, the cursor blinking and
+    -- failures flashing, for less distraction or slow ssh links
+    -- reduced_motion = true,
+
+    -- Move through the output with vim's gg, G, ctrl+d, ctrl+u and zz too.
+    -- z then starts these, fold with za
+    -- vim_navigation = true,
+
+    -- Edit the input like vi: esc for normal mode, with word motions and
+    -- edits such as cw, dd or ci", then i or a to type again
+    -- vi_input = true,
+}
+`,
+    "yaml": `# cmdtui configuration
+
+# Each button becomes an entry in the list on the left. cmd is the argv to
+# execute, no shell is involved unless you run one yourself.
+buttons:
+  - name: Say hello
+    cmd: [echo, hello from cmdtui]
+
+  # prompt asks for one more argument before running
+  - name: Search files
+    cmd: [grep, -rn]
+    prompt: true
+
+  # env adds variables to the command's environment
+  - name: Greet from env
+    cmd: [sh, -c, "echo $GREETING, $USER"]
+    env:
+      GREETING: Hi
+
+  # cwd runs the command in another directory. icon is shown before the
+  # name, or icon_fallback where the terminal can't draw it
+  - name: List /tmp
+    cmd: [ls, -la]
+    cwd: /tmp
+    icon: 📂
+    icon_fallback: ">"
+
+  # stdin is given to the command on its standard input, or with
+  # {file: deploy.yaml} the file, relative to cwd
+  - name: Count lines
+    cmd: [wc, -l]
+    stdin: |
+      one
+      two
+
+  # glob expands patterns like *.log to the matching files, which a shell
+  # would otherwise do
+  - name: Log sizes
+    cmd: [du, -h, "*.log"]
+    cwd: /var/log
+    glob: true
+
+  # progress turns matching output into a progress bar, notify sends a
+  # desktop notification when the command ran for at least 5 seconds
+  - name: Slow count
+    cmd: [sh, -c, 'for i in 1 2 3 4 5; do echo "$i/5"; sleep 1; done']
+    progress: '(\d+)/(\d+)'
+    notify: 5
+
+# Output tabs, switch between them with [ and ]. A tab can set its layout:
+# the button list left or right of the output, hidden, or viewport to show
+# nothing but the output. wrap: true wraps long lines instead of cutting
+# them off, w toggles it.
+tabs:
+  - Main
+  - name: Logs
+    layout: viewport
+    wrap: true
+
+# Optional layout, these are the defaults
+viewport: {width: 110, height: 20}
+list: {width: 45, height: 20}
+textinput: {width: 107}
+
+# Suggestions cycled with tab in the input box
+completions: [git status, git log --oneline, make]
+
+# Remap any binding by name
+# keys:
+#   quit: [q, ctrl+c]
+
+# high-contrast keeps to black, white and the brightest colors, for when
+# the default pinks and purples are hard to tell apart. --theme picks one
+# for a single run. styles changes are made on top of it
+# theme: high-contrast
+
+# Change any style by name: colors, bold, italic, underline, faint, reverse,
+# padding, margin, border and border_foreground
+# styles:
+#   active_button: {background: "#005f87", bold: true}
+#   focused_border: {border: rounded, border_foreground: {light: "25", dark: "39"}}
+
+# The border of every pane: normal, rounded, double, thick, hidden or none.
+# A table sets the list, output and input panes apart.
+# borders: rounded
+
+# How the status of commands is shown: unicode, nerd for Nerd Font icons,
+# ascii, or auto for unicode where the locale allows it
+# icons: nerd
+
+bell_on_failure: false
+flash_on_failure: true
+
+# Stop the spinner and progress bar moving, the cursor blinking and failures
+# flashing, for less distraction or slow ssh links
+# reduced_motion: true
+
+# Move through the output with vim's gg, G, ctrl+d, ctrl+u and zz too. z then
+# starts these, fold with za
+# vim_navigation: true
+
+# Edit the input like vi: esc for normal mode, with word motions and edits
+# such as cw, dd or ci", then i or a to type again
+# vi_input: true
+
+# Token requests to --http need as "Authorization: Bearer <token>". Without
+# it one is made up per session, see the README
+# http_token: change me
+`,
+    "toml": `# cmdtui configuration
+
+# Output tabs, switch between them with [ and ]
+tabs = ["Main", "Logs"]
+
+# Suggestions cycled with tab in the input box
+completions = ["git status", "git log --oneline", "make"]
+
+bell_on_failure = false
+flash_on_failure = true
+
+# Stop the spinner and progress bar moving, the cursor blinking and failures
+# flashing, for less distraction or slow ssh links
+# reduced_motion = true
+
+# Move through the output with vim's gg, G, ctrl+d, ctrl+u and zz too. z then
+# starts these, fold with za
+# vim_navigation = true
+
+# Edit the input like vi: esc for normal mode, with word motions and edits
+# such as cw, dd or ci", then i or a to type again
+# vi_input = true
+
+# Token requests to --http need as "Authorization: Bearer <token>". Without
+# it one is made up per session, see the README
+# http_token = "change me"
+
+# The border of every pane: normal, rounded, double, thick, hidden or none.
+# A table sets the list, output and input panes apart.
+# borders = "rounded"
+
+# high-contrast keeps to black, white and the brightest colors, for when
+# the default pinks and purples are hard to tell apart. --theme picks one
+# for a single run
+# theme = "high-contrast"
+
+# How the status of commands is shown: unicode, nerd for Nerd Font icons,
+# ascii, or auto for unicode where the locale allows it
+# icons = "nerd"
+
+# Optional layout, these are the defaults
+[viewport]
+width = 110
+height = 20
+
+[list]
+width = 45
+height = 20
+
+[textinput]
+width = 107
+
+# Remap any binding by name
+# [keys]
+# quit = ["q", "ctrl+c"]
+
+# Change any style by name: colors, bold, italic, underline, faint, reverse,
+# padding, margin, border and border_foreground
+# [styles.active_button]
+# background = "#005f87"
+# bold = true
+
+# Each button becomes an entry in the list on the left. cmd is the argv to
+# execute, no shell is involved unless you run one yourself.
+[[buttons]]
+name = "Say hello"
+cmd = ["echo", "hello from cmdtui"]
+
+# prompt asks for one more argument before running
+[[buttons]]
+name = "Search files"
+cmd = ["grep", "-rn"]
+prompt = true
+
+# env adds variables to the command's environment
+[[buttons]]
+name = "Greet from env"
+cmd = ["sh", "-c", "echo $GREETING, $USER"]
+env = { GREETING = "Hi" }
+
+# cwd runs the command in another directory
+[[buttons]]
+name = "List /tmp"
+cmd = ["ls", "-la"]
+cwd = "/tmp"
+
+# stdin is given to the command on its standard input, or with
+# { file = "deploy.yaml" } the file, relative to cwd
+[[buttons]]
+name = "Count lines"
+cmd = ["wc", "-l"]
+stdin = """
+one
+two
+"""
+
+# progress turns matching output into a progress bar, notify sends a
+# desktop notification when the command ran for at least 5 seconds
+[[buttons]]
+name = "Slow count"
+cmd = ["sh", "-c", 'for i in 1 2 3 4 5; do echo "$i/5"; sleep 1; done']
+progress = '(\d+)/(\d+)'
+notify = 5
+`,
+    "json": `{
+  "buttons": [
+    { "name": "Say hello", "cmd": ["echo", "hello from cmdtui"] },
+    { "name": "Search files", "cmd": ["grep", "-rn"], "prompt": true },
+    { "name": "Greet from env", "cmd": ["sh", "-c", "echo $GREETING, $USER"], "env": { "GREETING": "Hi" } },
+    { "name": "List /tmp", "cmd": ["ls", "-la"], "cwd": "/tmp" },
+    {
+      "name": "Slow count",
+      "cmd": ["sh", "-c", "for i in 1 2 3 4 5; do echo \"$i/5\"; sleep 1; done"],
+      "progress": "(\\d+)/(\\d+)",
+      "notify": 5
+    }
+  ],
+  "tabs": ["Main", "Logs"],
+  "viewport": { "width": 110, "height": 20 },
+  "list": { "width": 45, "height": 20 },
+  "textinput": { "width": 107 },
+  "completions": ["git status", "git log --oneline", "make"],
+  "bell_on_failure": false,
+  "flash_on_failure": true
+}
+`,
+}
+
+// userConfigDir returns the directory cmdtui keeps its config in,
+// $XDG_CONFIG_HOME/cmdtui or ~/.config/cmdtui.
+func userConfigDir() (string, error) {
+    if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+        return filepath.Join(xdg, "cmdtui"), nil
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return "", err
+    }
+    return filepath.Join(home, ".config", "cmdtui"), nil
+}
+
+// runInit implements `cmdtui init`, writing a starter config to the user
+// config directory.
+func runInit(args []string) error {
+    fs := flag.NewFlagSet("init", flag.ExitOnError)
+    format := fs.String("format", "lua", "config format: lua, yaml, toml or json")
+    force := fs.Bool("force", false, "overwrite an existing config")
+    fs.Parse(args)
+
+    content, ok := starterConfigs[*format]
+    if !ok {
+        return fmt.Errorf("unknown format %q, expected lua, yaml, toml or json", *format)
+    }
+
+    dir, err := userConfigDir()
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(dir, 0o755); err != nil {
+        return err
+    }
+
+    path := filepath.Join(dir, "config."+*format)
+    flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+    if !*force {
+        flags |= os.O_EXCL
+    }
+    f, err := os.OpenFile(path, flags, 0o644)
+    if errors.Is(err, os.ErrExist) {
+        return fmt.Errorf("%s already exists, pass --force to overwrite it", path)
+    } else if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    if _, err := f.WriteString(content); err != nil {
+        return err
+    }
+    fmt.Printf("Wrote %s\n", path)
+    return nil
+}
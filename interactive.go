@@ -0,0 +1,54 @@
+package main
+
+import (
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    lua "github.com/yuin/gopher-lua"
+)
+
+// interactiveFinishedMsg is sent when an interactive command exits and the
+// TUI has the terminal back.
+type interactiveFinishedMsg struct {
+    cmd     command
+    tab     int
+    err     error
+    elapsed time.Duration
+}
+
+// runInteractive suspends the TUI and gives the terminal to cmd, for full
+// screen programs such as editors, top or an ssh session. Nothing is
+// captured, a summary is written to the current tab once it exits.
+func (m *model) runInteractive(cmd command) tea.Cmd {
+    tab := m.currentTab
+    started := time.Now()
+    remote := cmd.elevate().remote()
+    c := remote.prepare(remote.cmd)
+    closeStdin, err := cmd.stdin.feed(c)
+    if err != nil {
+        return func() tea.Msg {
+            return interactiveFinishedMsg{cmd: cmd, tab: tab, err: err}
+        }
+    }
+    run := tea.ExecProcess(c, func(err error) tea.Msg {
+        closeStdin()
+        return interactiveFinishedMsg{cmd: cmd, tab: tab, err: err, elapsed: time.Since(started)}
+    })
+    if m.script != nil {
+        return tea.Batch(m.runHook("on_start", m.script.commandValue(cmd)), run)
+    }
+    return run
+}
+
+func (m *model) interactiveFinished(msg interactiveFinishedMsg) tea.Cmd {
+    m.recordRun(msg.cmd.name, msg.err, msg.elapsed)
+    footer := runFooter(msg.cmd.name, msg.err, msg.elapsed)
+    m.appendOutput(msg.tab, footer)
+    cmds := []tea.Cmd{m.announce(strings.TrimSuffix(footer, "\n"))}
+    if msg.err != nil {
+        cmds = append(cmds, m.alertFailure(msg.tab))
+    }
+    cmds = append(cmds, m.runHook("on_exit", lua.LNumber(exitCode(msg.err)), lua.LString(msg.cmd.name)))
+    return tea.Batch(cmds...)
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+    "errors"
+    "io"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/creack/pty"
+)
+
+// job tracks a command started by runCommand until it exits.
+type job struct {
+    id      int
+    tab     int
+    cmd     command
+    started time.Time
+    events  chan tea.Msg
+
+    captured strings.Builder // All output so far, for the button's transform and to compare runs
+    stdout   strings.Builder // Standard output alone
+    stderr   strings.Builder // Standard error alone, empty on a pseudo-terminal
+
+    size  pty.Winsize    // Terminal size for commands run on a pseudo-terminal
+    pty   *os.File       // The pseudo-terminal, nil unless the button asked for one
+    input bool           // Keep standard input open for lines typed in the TUI, otherwise it is empty
+    stdin io.WriteCloser // Where typed lines go without a pseudo-terminal, nil once closed
+
+    procs  []*exec.Cmd   // The processes started, to stop them
+    exited chan struct{} // Closed once the processes have exited
+
+    maxShown int      // Bytes of output shown before the rest goes to a file, 0 for no limit
+    overflow overflow // See send
+
+    queuedMu sync.Mutex
+    queued   []commandOutputMsg // Output read since it was last passed on, see queue
+}
+
+// commandOutputMsg carries the lines of output read in one go from a
+// running job, or a notice about its output.
+type commandOutputMsg struct {
+    job    int
+    lines  []string
+    stderr bool   // Read from standard error rather than standard output
+    notice string // Shown as it is, such as where output past the limit went
+}
+
+// commandFinishedMsg is sent when a job's process exits.
+type commandFinishedMsg struct {
+    job int
+    err error
+}
+
+// variableRef matches $VAR and ${VAR}.
+var variableRef = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+))`)
+
+// expand returns cmd with $VAR and ${VAR} references in its arguments and
+// working directory replaced, looking in the button's env before the
+// process environment. Unknown variables are left alone so scripts run
+// through a shell keep their own. A leading ~ in the working directory or
+// stdin file is the home directory.
+func (cmd command) expand() command {
+    replace := func(s string) string {
+        return variableRef.ReplaceAllStringFunc(s, func(ref string) string {
+            match := variableRef.FindStringSubmatch(ref)
+            name := match[1] + match[2]
+            for _, pair := range cmd.env {
+                if strings.HasPrefix(pair, name+"=") {
+                    return pair[len(name)+1:]
+                }
+            }
+            if value, ok := os.LookupEnv(name); ok {
+                return value
+            }
+            return ref
+        })
+    }
+
+    argv := make([]string, len(cmd.cmd))
+    for i, arg := range cmd.cmd {
+        argv[i] = replace(arg)
+    }
+    cmd.cmd = argv
+
+    cmd.dir = expandHome(replace(cmd.dir))
+    // A relative stdin file is in the working directory, unless that is on
+    // another host, the file being read here
+    if file := expandHome(replace(cmd.stdin.file)); file != "" && !filepath.IsAbs(file) && cmd.dir != "" && cmd.target == "" {
+        cmd.stdin.file = filepath.Join(cmd.dir, file)
+    } else {
+        cmd.stdin.file = file
+    }
+    return cmd
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) string {
+    if path == "~" || strings.HasPrefix(path, "~/") {
+        if home, err := os.UserHomeDir(); err == nil {
+            return home + path[1:]
+        }
+    }
+    return path
+}
+
+// prepare sets up argv to run in the button's directory and environment,
+// with its globs expanded when the button asks for it.
+func (cmd command) prepare(argv []string) *exec.Cmd {
+    if cmd.expandsGlobs() {
+        argv = expandGlobs(argv, cmd.dir)
+    }
+    c := exec.Command(argv[0], argv[1:]...)
+    c.Dir = cmd.dir
+    if len(cmd.env) > 0 {
+        c.Env = append(os.Environ(), cmd.env...)
+    }
+    return c
+}
+
+// start launches the process for argv and streams its standard output and
+// error as commandOutputMsg values, followed by a single commandFinishedMsg.
+// Lines of the two streams are sent as they are read, which can differ a
+// little from the order they were written in, and past maxShown go to a
+// file instead. Output is passed on every outputInterval. When the command has a pipe,
+// the standard output of argv feeds the pipe's standard input and the output
+// shown is the pipe's along with argv's errors. Otherwise buttons can ask for
+// a pseudo-terminal instead of plain pipes, which has one stream for both.
+func (j *job) start(argv []string) tea.Cmd {
+    j.events = make(chan tea.Msg, 64)
+    j.exited = make(chan struct{})
+    j.started = time.Now()
+
+    pr, pw := io.Pipe()
+    er, ew := io.Pipe()
+    c := j.cmd.prepare(argv)
+    c.Stdout = pw
+    c.Stderr = ew
+
+    closeStdin, err := j.cmd.stdin.feed(c)
+    if j.input && !j.cmd.pty && !j.cmd.stdin.isSet() {
+        // Otherwise the command reads nothing. Failing to make the pipe
+        // only leaves it that way, sendInput reports the input as closed
+        j.stdin, _ = c.StdinPipe()
+    }
+
+    var wait func() error
+    switch {
+    case err != nil:
+        // The stdin file couldn't be opened, which fails the run below
+    case len(j.cmd.pipe) > 0:
+        wait, err = j.startPipe(c, pw, ew)
+    case j.cmd.pty:
+        wait, err = j.startPTY(c, pw)
+    default:
+        setProcessGroup(c)
+        wait, err = c.Wait, c.Start()
+    }
+    closeStdin()
+    j.procs = append(j.procs, c)
+    if err != nil {
+        pw.Close()
+        ew.Close()
+        close(j.exited)
+        j.events <- commandFinishedMsg{job: j.id, err: err}
+        close(j.events)
+        return j.wait()
+    }
+
+    done := make(chan error, 1)
+    go func() {
+        // Closing the writers once the process is gone ends the reads below
+        done <- wait()
+        close(j.exited)
+        pw.Close()
+        ew.Close()
+    }()
+
+    var read sync.WaitGroup
+    readOutput := func(r io.Reader, stderr bool) {
+        defer read.Done()
+        j.readOutput(r, stderr)
+    }
+    read.Add(2)
+    go readOutput(pr, false)
+    go readOutput(er, true)
+    allRead := make(chan struct{})
+    go func() {
+        read.Wait()
+        close(allRead)
+    }()
+    go func() {
+        j.passOutput(allRead)
+        j.closeOverflow()
+        j.events <- commandFinishedMsg{job: j.id, err: <-done}
+        close(j.events)
+    }()
+
+    return j.wait()
+}
+
+// startPipe starts c with its standard output connected to the job's pipe
+// command, which writes to out and errOut. The returned function waits for
+// both and reports the pipe command's failure first, like a shell would.
+func (j *job) startPipe(c *exec.Cmd, out, errOut io.Writer) (func() error, error) {
+    next := j.cmd.prepare(j.cmd.pipe)
+    next.Stdout = out
+    next.Stderr = errOut
+    setProcessGroup(c)
+    setProcessGroup(next)
+
+    r, w, err := os.Pipe()
+    if err != nil {
+        return nil, err
+    }
+    c.Stdout = w
+    next.Stdin = r
+    defer r.Close()
+    defer w.Close()
+
+    if err := next.Start(); err != nil {
+        return nil, err
+    }
+    j.procs = append(j.procs, next)
+    if err := c.Start(); err != nil {
+        w.Close()
+        next.Wait()
+        return nil, err
+    }
+    return func() error {
+        err := c.Wait()
+        if nextErr := next.Wait(); nextErr != nil {
+            return nextErr
+        }
+        return err
+    }, nil
+}
+
+// startPTY starts c on a new pseudo-terminal, for programs that behave
+// differently when they aren't talking to a terminal, and copies everything
+// it prints to out. The terminal's session is a process group of its own.
+func (j *job) startPTY(c *exec.Cmd, out io.Writer) (func() error, error) {
+    c.Stdout, c.Stderr = nil, nil
+    if c.Stdin != nil {
+        // Standard input is the button's stdin rather than the terminal
+        setTerminalOnStdout(c)
+    }
+    f, err := pty.StartWithSize(c, &j.size)
+    if err != nil {
+        return nil, err
+    }
+    j.pty = f
+
+    copied := make(chan struct{})
+    go func() {
+        // Reading fails once nothing holds the terminal open any more
+        io.Copy(out, f)
+        close(copied)
+    }()
+    return func() error {
+        err := c.Wait()
+        <-copied
+        f.Close()
+        return err
+    }, nil
+}
+
+// stop signals the job's processes and everything they started to
+// terminate, or kills them when force is set.
+func (j *job) stop(force bool) {
+    for _, c := range j.procs {
+        stopProcess(c, force)
+    }
+}
+
+// sendInput writes a line typed by the user to the job's pseudo-terminal or
+// standard input.
+func (j *job) sendInput(line string) error {
+    if j.pty != nil {
+        _, err := io.WriteString(j.pty, line+"\r")
+        return err
+    }
+    if j.stdin == nil {
+        return errors.New("standard input is closed")
+    }
+    _, err := io.WriteString(j.stdin, line+"\n")
+    return err
+}
+
+// closeInput closes the job's standard input, the end of file many programs
+// wait for before finishing.
+func (j *job) closeInput() error {
+    if j.stdin == nil {
+        return errors.New("standard input is already closed")
+    }
+    err := j.stdin.Close()
+    j.stdin = nil
+    return err
+}
+
+// wait returns a tea.Cmd that delivers the job's next event.
+func (j *job) wait() tea.Cmd {
+    return func() tea.Msg {
+        msg, ok := <-j.events
+        if !ok {
+            return nil
+        }
+        return msg
+    }
+}
+
+// parseProgress extracts a completion ratio from line using the job's
+// progress pattern. A single capture group is read as a percentage, two
+// groups as "current" and "total".
+func (j *job) parseProgress(line string) (float64, bool) {
+    if j.cmd.progress == nil {
+        return 0, false
+    }
+    match := j.cmd.progress.FindStringSubmatch(line)
+    if len(match) < 2 {
+        return 0, false
+    }
+    value, err := strconv.ParseFloat(match[1], 64)
+    if err != nil {
+        return 0, false
+    }
+    if len(match) >= 3 {
+        total, err := strconv.ParseFloat(match[2], 64)
+        if err != nil || total == 0 {
+            return 0, false
+        }
+        return value / total, true
+    }
+    return value / 100, true
+}
+
+// exitCode returns the exit status described by a commandFinishedMsg error:
+// 0 on success, the process's code if it ran, -1 if it never started.
+func exitCode(err error) int {
+    if err == nil {
+        return 0
+    }
+    var exitErr *exec.ExitError
+    if errors.As(err, &exitErr) {
+        return exitErr.ExitCode()
+    }
+    return -1
+}
+
+// shouldNotify reports whether finishing the job after elapsed warrants a
+// desktop notification.
+func (j *job) shouldNotify(elapsed time.Duration) bool {
+    return j.cmd.notify && elapsed >= j.cmd.notifyAfter
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "gopkg.in/yaml.v3"
+)
+
+// kubeConfig is the part of a kubeconfig file cmdtui reads.
+type kubeConfig struct {
+    CurrentContext string `yaml:"current-context"`
+    Contexts       []struct {
+        Name    string `yaml:"name"`
+        Context struct {
+            Namespace string `yaml:"namespace"`
+        } `yaml:"context"`
+    } `yaml:"contexts"`
+}
+
+// kubeConfigFiles returns the kubeconfig files kubectl would use, the ones
+// in $KUBECONFIG or ~/.kube/config.
+func kubeConfigFiles() []string {
+    if env := os.Getenv("KUBECONFIG"); env != "" {
+        return filepath.SplitList(env)
+    }
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return nil
+    }
+    return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// loadKubeConfig merges the kubeconfig files the way kubectl does: the
+// first file to set the current context wins, and so does the first
+// context with a given name.
+func loadKubeConfig() (kubeConfig, error) {
+    var merged kubeConfig
+    seen := make(map[string]bool)
+    found := false
+    for _, path := range kubeConfigFiles() {
+        data, err := os.ReadFile(path)
+        if os.IsNotExist(err) {
+            continue
+        } else if err != nil {
+            return kubeConfig{}, err
+        }
+        found = true
+
+        var cfg kubeConfig
+        if err := yaml.Unmarshal(data, &cfg); err != nil {
+            return kubeConfig{}, fmt.Errorf("%s: %v", path, err)
+        }
+        if merged.CurrentContext == "" {
+            merged.CurrentContext = cfg.CurrentContext
+        }
+        for _, ctx := range cfg.Contexts {
+            if !seen[ctx.Name] {
+                seen[ctx.Name] = true
+                merged.Contexts = append(merged.Contexts, ctx)
+            }
+        }
+    }
+    if !found {
+        return kubeConfig{}, fmt.Errorf("no kubeconfig found in %s", strings.Join(kubeConfigFiles(), ", "))
+    }
+    return merged, nil
+}
+
+// kubeContexts lists the contexts in the kubeconfig, marking the current one.
+func kubeContexts(command, map[string]string) ([]string, error) {
+    cfg, err := loadKubeConfig()
+    if err != nil {
+        return nil, err
+    }
+    var lines []string
+    for _, ctx := range cfg.Contexts {
+        line := ctx.Name
+        if ctx.Name == cfg.CurrentContext {
+            line += "  (current)"
+        }
+        lines = append(lines, line)
+    }
+    return lines, nil
+}
+
+// kubeNamespaces lists the namespaces of the picked context, or the current
+// one. They are asked from the cluster with kubectl, and when that fails the
+// namespaces named in the kubeconfig are offered instead.
+func kubeNamespaces(_ command, picked map[string]string) ([]string, error) {
+    cfg, err := loadKubeConfig()
+    if err != nil {
+        return nil, err
+    }
+    context := cfg.CurrentContext
+    if ctx, ok := picked["context"]; ok {
+        context = ctx
+    }
+
+    out, err := exec.Command("kubectl", "--context", context, "get", "namespaces", "-o", "name").Output()
+    if err == nil {
+        var lines []string
+        for _, name := range strings.Fields(string(out)) {
+            lines = append(lines, strings.TrimPrefix(name, "namespace/"))
+        }
+        return lines, nil
+    }
+
+    lines := []string{"default"}
+    seen := map[string]bool{"default": true}
+    for _, ctx := range cfg.Contexts {
+        if ns := ctx.Context.Namespace; ns != "" && !seen[ns] {
+            seen[ns] = true
+            lines = append(lines, ns)
+        }
+    }
+    return lines, nil
+}
+
+// switchKube forgets the picked Kubernetes context and namespace and asks
+// for new ones, which commands then see as {context} and {namespace}.
+func (m *model) switchKube() tea.Cmd {
+    delete(m.picked, "context")
+    delete(m.picked, "namespace")
+    cmd := command{name: "Kubernetes switch", cmd: []string{"{context}", "{namespace}"}}
+    return m.fillPlaceholders(cmd, func(m *model, _ command) tea.Cmd {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Switched to Kubernetes context %s, namespace %s\n", m.picked["context"], m.picked["namespace"]))
+        return nil
+    })
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+    "sort"
+    "strings"
+
+    "github.com/charmbracelet/lipgloss"
+)
+
+// tabLayout decides which panes are shown while a tab is active.
+type tabLayout int
+
+const (
+    layoutLeft     tabLayout = iota // Button list left of the output, the default
+    layoutRight                     // Button list right of the output
+    layoutHidden                    // No button list, the output takes its width
+    layoutViewport                  // Only the output, using the whole window
+)
+
+// tabLayouts maps the names used in the config to layouts.
+var tabLayouts = map[string]tabLayout{
+    "left":     layoutLeft,
+    "right":    layoutRight,
+    "hidden":   layoutHidden,
+    "viewport": layoutViewport,
+}
+
+// tabConfig is a tab as declared in the config.
+type tabConfig struct {
+    title  string
+    layout tabLayout
+    wrap   bool // Start with long lines wrapped
+}
+
+// extractTabs reads the tabs list. Each entry is either a title or a table
+// with a name, a layout and whether to wrap long lines.
+func extractTabs(node configNode) []tabConfig {
+    var tabs []tabConfig
+    for _, item := range node.items() {
+        if _, ok := item.value.(map[string]interface{}); !ok {
+            tabs = append(tabs, tabConfig{title: item.requiredStr()})
+            continue
+        }
+        title := item.key("name").requiredStr()
+        item = item.anchored(title)
+        tabs = append(tabs, tabConfig{title: title, layout: extractLayout(item.key("layout")), wrap: item.key("wrap").boolean(false)})
+    }
+    return tabs
+}
+
+func extractLayout(node configNode) tabLayout {
+    name := node.str("left")
+    layout, ok := tabLayouts[name]
+    if !ok {
+        names := make([]string, 0, len(tabLayouts))
+        for n := range tabLayouts {
+            names = append(names, n)
+        }
+        sort.Strings(names)
+        node.fail("unknown layout %q, expected one of %s", name, strings.Join(names, ", "))
+    }
+    return layout
+}
+
+// showsList and showsInput report which panes a layout has besides the output.
+func (l tabLayout) showsList() bool  { return l == layoutLeft || l == layoutRight }
+func (l tabLayout) showsInput() bool { return l != layoutViewport }
+
+// layoutOf returns the layout of tab, which zen mode overrides to show
+// nothing but the output.
+func (m *model) layoutOf(tab int) tabLayout {
+    if m.zen {
+        return layoutViewport
+    }
+    return m.tabs[tab].layout
+}
+
+// toggleZen switches zen mode, where the output takes the whole terminal.
+func (m *model) toggleZen() {
+    m.zen = !m.zen
+    m.resizeTabs()
+}
+
+// resizeTabs sizes every tab's viewport for its layout, then divides the
+// space between the panes of a split. Output is fitted to the new width.
+func (m *model) resizeTabs() {
+    for i := range m.tabs {
+        m.tabs[i].viewport.Width, m.tabs[i].viewport.Height = m.outputSize(m.layoutOf(i))
+    }
+    m.resizeSplit()
+    for i := range m.tabs {
+        if m.tabs[i].lines != nil {
+            m.tabs[i].showOutput()
+        }
+    }
+    m.showComparison()
+}
+
+// toggleWrap switches wrapping long lines in the current tab.
+func (m *model) toggleWrap() {
+    t := &m.tabs[m.currentTab]
+    t.wrap = !t.wrap
+    if t.lines != nil {
+        t.showOutput()
+    }
+}
+
+// outputSize is the size of the output viewport in layout. The output grows
+// into the space of the panes the layout leaves out. In zen mode it fills
+// the terminal, once its size is known.
+func (m *model) outputSize(layout tabLayout) (width, height int) {
+    frameWidth, frameHeight := paneStyle(focusViewport, false).GetFrameSize()
+    if m.zen && m.width > 0 {
+        // Leave a line for the status of running commands
+        return m.width - docStyle.GetHorizontalFrameSize() - frameWidth, m.height - docStyle.GetVerticalFrameSize() - frameHeight - 1
+    }
+    width = m.vpDimensions.width
+    inputFrame := paneStyle(focusInput, false).GetVerticalFrameSize()
+    height = m.vpDimensions.height - m.tiDimensions.height - inputFrame
+    if !layout.showsList() {
+        width += m.listDimensions.width + paneStyle(focusList, false).GetHorizontalFrameSize()
+    }
+    if !layout.showsInput() {
+        height += m.tiDimensions.height + inputFrame
+    }
+    return width, height
+}
+
+// visible reports whether the pane for f is shown in the current tab. The
+// input is always reachable while a button waits for its argument.
+func (m *model) visible(f focusState) bool {
+    layout := m.layoutOf(m.layoutTab())
+    switch f {
+    case focusList:
+        return layout.showsList()
+    case focusInput:
+        return layout.showsInput() || m.prompInput || m.pipeSource != nil
+    }
+    return true
+}
+
+// cycleFocus moves focus by step panes, skipping the ones the current tab's
+// layout hides.
+func (m *model) cycleFocus(step int) {
+    for i := 0; i < 3; i++ {
+        m.focus = (m.focus + focusState(step) + 3) % 3
+        if m.visible(m.focus) {
+            return
+        }
+    }
+}
+
+// fixFocus moves focus to the output when the focused pane is hidden, after
+// switching tabs for example.
+func (m *model) fixFocus() {
+    if !m.visible(m.focus) {
+        m.focus = focusViewport
+    }
+}
+
+// layoutView arranges the rendered panes for the current tab's layout.
+func (m *model) layoutView(listView, viewportView, inputView string) string {
+    layout := m.layoutOf(m.layoutTab())
+    output := viewportView
+    if m.visible(focusInput) {
+        output = lipgloss.JoinVertical(lipgloss.Left, viewportView, inputView)
+    }
+    switch layout {
+    case layoutLeft:
+        return lipgloss.JoinHorizontal(lipgloss.Top, listView, output)
+    case layoutRight:
+        return lipgloss.JoinHorizontal(lipgloss.Top, output, listView)
+    }
+    return output
+}
+
+// Limits for resizing panes at runtime.
+const (
+    minListWidth      = 10
+    minViewportWidth  = 20
+    minViewportHeight = 3
+)
+
+// resizePanes moves the boundary between the button list and the output by
+// dw columns, growing the list for positive values, and the one between the
+// output and the input by dh rows, growing the output for positive values.
+func (m *model) resizePanes(dw, dh int) {
+    if dw != 0 && m.listDimensions.width+dw >= minListWidth && m.vpDimensions.width-dw >= minViewportWidth {
+        m.listDimensions.width += dw
+        m.vpDimensions.width -= dw
+        m.tiDimensions.width -= dw
+        m.list.SetSize(m.listDimensions.width, m.listDimensions.height)
+        m.input.Width = m.tiDimensions.width
+    }
+    if dh != 0 && m.tiDimensions.height-dh >= 1 && m.vpDimensions.height-m.tiDimensions.height+dh-4 >= minViewportHeight {
+        m.tiDimensions.height -= dh
+    }
+    m.resizeTabs()
+}
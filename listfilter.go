@@ -0,0 +1,56 @@
+package main
+
+import (
+    "github.com/charmbracelet/bubbles/list"
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// buttonFilterMsg carries the buttons matching a filter, numbered in the
+// order the filters were started. The list filters in the background, and
+// with many buttons the matches for an earlier keystroke can arrive after
+// those for a later one.
+type buttonFilterMsg struct {
+    seq     int
+    matches list.FilterMatchesMsg
+}
+
+// numberFilters wraps a command from the list so the filter matches it may
+// deliver arrive as a numbered buttonFilterMsg.
+func (m *model) numberFilters(cmd tea.Cmd) tea.Cmd {
+    if cmd == nil {
+        return nil
+    }
+    m.filterSeq++
+    return numberFilter(cmd, m.filterSeq)
+}
+
+func numberFilter(cmd tea.Cmd, seq int) tea.Cmd {
+    return func() tea.Msg {
+        switch msg := cmd().(type) {
+        case list.FilterMatchesMsg:
+            return buttonFilterMsg{seq: seq, matches: msg}
+        case tea.BatchMsg:
+            numbered := make(tea.BatchMsg, len(msg))
+            for i, c := range msg {
+                if c != nil {
+                    numbered[i] = numberFilter(c, seq)
+                }
+            }
+            return numbered
+        default:
+            return msg
+        }
+    }
+}
+
+// showFilter hands the matches of msg to the list, unless it already shows
+// those of a later filter.
+func (m *model) showFilter(msg buttonFilterMsg) tea.Cmd {
+    if msg.seq < m.filterShown {
+        return nil
+    }
+    m.filterShown = msg.seq
+    var cmd tea.Cmd
+    m.list, cmd = m.list.Update(msg.matches)
+    return m.numberFilters(cmd)
+}
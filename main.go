@@ -1,36 +1,68 @@
 package main
 
 import (
-    "bytes"
+    "flag"
     "fmt"
     "io"
     "log"
-    "os/exec"
+    "os"
+    "regexp"
     "strings"
+    "time"
 
     tea "github.com/charmbracelet/bubbletea"
     "github.com/charmbracelet/bubbles/list"
-    "github.com/charmbracelet/bubbles/viewport"
+    "github.com/charmbracelet/bubbles/paginator"
+    "github.com/charmbracelet/bubbles/progress"
+    "github.com/charmbracelet/bubbles/spinner"
+    "github.com/charmbracelet/bubbles/textarea"
     "github.com/charmbracelet/bubbles/textinput"
     help "github.com/charmbracelet/bubbles/help"
     key "github.com/charmbracelet/bubbles/key"
     "github.com/charmbracelet/lipgloss"
-    lua "github.com/yuin/gopher-lua"
+    "github.com/creack/pty"
     fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+    "github.com/muesli/termenv"
+    lua "github.com/yuin/gopher-lua"
+)
+
+// The palette. Each color has a shade for dark terminals and one that
+// stays legible on light ones, picked by the terminal's background.
+var (
+    accentColor   = lipgloss.AdaptiveColor{Light: "162", Dark: "205"}
+    onAccentColor = lipgloss.AdaptiveColor{Light: "231", Dark: "230"} // Text on accentColor
+    mutedColor    = lipgloss.AdaptiveColor{Light: "61", Dark: "62"}
+    failureColor  = lipgloss.AdaptiveColor{Light: "160", Dark: "196"}
+    successColor  = lipgloss.AdaptiveColor{Light: "28", Dark: "42"}
+    elevatedColor = lipgloss.AdaptiveColor{Light: "166", Dark: "208"}
+    infoColor     = lipgloss.AdaptiveColor{Light: "25", Dark: "39"}
+    codeColor     = lipgloss.AdaptiveColor{Light: "130", Dark: "214"}
+    quoteColor    = lipgloss.AdaptiveColor{Light: "242", Dark: "245"}
 )
 
 var (
     docStyle       = lipgloss.NewStyle().Margin(1, 2)
     normalBorder   = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).Padding(1)
-    focusedBorder  = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), true).Padding(1).BorderForeground(lipgloss.Color("205"))
-    activeButton   = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("230")).Background(lipgloss.Color("205"))
-    inactiveButton = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("62"))
+    focusedBorder  = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), true).Padding(1).BorderForeground(accentColor)
+    activeButton   = lipgloss.NewStyle().Padding(0, 1).Foreground(onAccentColor).Background(accentColor)
+    inactiveButton = lipgloss.NewStyle().Padding(0, 1).Foreground(mutedColor)
     helpText       = "Press tab to switch focus. Press enter to execute the command. Press q to quit. Press / to filter the output. Press ctrl+l to refresh."
     tabBorder      = lipgloss.NewStyle().Border(lipgloss.NormalBorder())
-    activeTabBorder = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), true).BorderForeground(lipgloss.Color("205"))
+    activeTabBorder = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), true).BorderForeground(accentColor)
     tab            = lipgloss.NewStyle().Padding(0, 1)
-    activeTab      = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("205")).Bold(true)
+    activeTab      = lipgloss.NewStyle().Padding(0, 1).Foreground(accentColor).Bold(true)
     tabGap         = tab.Copy().Padding(0, 2)
+    spinnerStyle   = lipgloss.NewStyle().Foreground(accentColor)
+    statusStyle    = lipgloss.NewStyle().Foreground(mutedColor)
+    errorStyle     = lipgloss.NewStyle().Foreground(failureColor)
+    pinnedDivider  = lipgloss.NewStyle().Underline(true).UnderlineSpaces(true).Foreground(mutedColor)
+    pinMarker      = "★ "
+    elevatedStyle  = lipgloss.NewStyle().Foreground(elevatedColor)
+    elevatedButton = inactiveButton.Copy().Foreground(elevatedColor)
+    elevatedBanner = lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("16")).Background(elevatedColor).Bold(true)
+    listTitle      = list.DefaultStyles().Title
+    helpKey        = help.New().Styles.ShortKey
+    helpDescription = help.New().Styles.ShortDesc
 )
 
 
@@ -43,9 +75,37 @@ const (
 )
 
 type command struct {
-    name   string
-    cmd    []string
-    prompt bool
+    name     string
+    cmd      []string
+    prompt   bool
+    progress *regexp.Regexp
+    notify      bool             // Send a desktop notification on completion
+    notifyAfter time.Duration    // Only notify when the command ran at least this long
+    dir         string           // Working directory, the current one if empty
+    env         []string         // Extra KEY=VALUE pairs added to the environment
+    transform   *lua.LFunction   // Rewrites the complete output before it is shown
+    completions completionSource // Suggestions for the prompted argument
+    pipe        []string         // Command that receives the output on its standard input
+    promptSpec  promptSpec       // Label and validation for the prompted argument
+    pty         bool             // Run on a pseudo-terminal so the command sees a TTY
+    interactive bool             // Hand the whole terminal to the command while it runs
+    input       bool             // Keep standard input open for lines typed in stdin mode
+    sudo        bool             // Run as root, asking for the password in the input box
+    target      string           // Host to run on over ssh, or the name of one in targets
+    tmuxTarget  string           // tmux pane to type the command into instead of running it
+    renderer    renderer         // Formats the output for display, nil to show it as it is
+    watchPaths  []string         // Files and directories that re-run the command when they change
+    group       string           // Shown before the name in the list, e.g. "make" for imported targets
+    description string           // Shown next to the button while it is selected
+    tags        []string         // Categories the list can be narrowed down to
+    glob        *bool            // Expand *.log style arguments before running, nil to follow the config
+    capture     captureSpec      // Variable the output is kept in, for {name} in later commands
+    enabled     *lua.LFunction   // Hides the button while it returns false
+    confirm     string           // Phrase to type before the button runs, for destructive commands
+    generator   *generator       // Set on the stand-in for a generator's buttons, see generator.standIn
+    icon        string           // Shown before the name, see shownIcon
+    plainIcon   string           // Shown instead of icon where the terminal can't draw it
+    stdin       stdinSpec        // Given to the command on its standard input
 }
 
 type dimensions struct {
@@ -54,23 +114,189 @@ type dimensions struct {
 }
 
 type model struct {
-    list           list.Model
-    viewports      []viewport.Model // Change to slice of viewports
-    input          textinput.Model
-    output         string
-    focus          focusState
-    commands       []command
-    showHelp       bool
-    vpDimensions   dimensions
-    listDimensions dimensions
-    tiDimensions   dimensions
-    completions    []string
-    currentIndex   int
-    help           help.Model
-    keys           keyMap
-    prompInput     bool
-    currentTab     int // Current tab index
-    tabs           []string // Tabs titles
+    list              list.Model
+    input             textinput.Model
+    focus             focusState
+    commands          []command // Buttons in the list, the shared ones followed by the profile's
+    sharedCommands    []command
+    profiles          []profile
+    profile           int  // Index of the active profile, -1 for none
+    cheatSheet        bool // Every binding is shown over the whole screen
+    editing           bool // The multi-line editor is open, see openEditor
+    editor            textarea.Model
+    vpDimensions      dimensions
+    listDimensions    dimensions
+    tiDimensions      dimensions
+    completions       completionSource
+    sharedCompletions completionSource
+    currentIndex      int      // Button waiting for its prompted argument
+    suggestions       []string // Suggestions being cycled with tab, nil when not cycling
+    suggestion        int      // Index into suggestions
+    help              help.Model
+    keys              keyMap
+    prompInput        bool
+    currentTab        int // Current tab index
+    tabs              []outputTab
+    spinner           spinner.Model
+    progress          progress.Model
+    jobs              []*job // Commands currently executing
+    nextJobID         int
+    progressJob       int     // ID of the job driving the progress bar, 0 if none
+    progressPercent   float64 // Where the progress bar is going
+    reducedMotion     bool    // Nothing moves unless it has to, see setReducedMotion
+    vimNavigation     bool    // The output takes vim's keys too, see vimMotion
+    vimPending        string  // First key of a vim motion, waiting for the second
+    viInput           bool    // The input is edited like vi, see viKey
+    viNormal          bool    // The input is in vi's normal mode
+    viPending         string  // Keys of a vi command that needs more of them
+    viRegister        string  // Text the last vi delete, change or yank took
+    viUndo            []viState
+    bellOnFailure     bool
+    flashOnFailure    bool
+    glob              bool // Expand globs for typed commands and buttons that don't choose
+    tabMemory         int  // Bytes of output a tab keeps in memory before moving the oldest to disk
+    flashTab          int  // Tab whose title and border flash after a failure
+    flashID           int  // Incremented per flash so stale flashEndMsgs are ignored
+    flashing          bool
+    ringing           bool // The view starts with BEL, see ringBell
+    watcher           *configWatcher // Reloads the config when it changes, nil if unavailable
+    control           *controlServer // Takes requests from other programs, nil unless --listen or --http was given
+    script            *scripting     // Live Lua state of a Lua config, nil otherwise
+    initCmd           tea.Cmd        // Work queued while building the model, started by Init
+    split             splitMode
+    splitTabs         [2]int // Tabs shown in the two panes of a split
+    splitPane         int    // Pane of splitTabs holding the current tab
+    zen               bool   // Only the output is shown, filling the terminal
+    width             int    // Terminal size, 0 until the first tea.WindowSizeMsg
+    height            int
+    pipeSource        *command // Button whose output the input's command will receive
+    aliases           map[string][]string
+    promptError       string               // Why the typed argument was rejected, shown under the input
+    targets           map[string]string    // Hosts by name, see extractTargets
+    picked            map[string]string    // Values kept for sticky placeholders such as {context}
+    tmuxTarget        string               // Pane for commands that don't name their own, "" to run them here
+    lastRuns          map[string]runResult // How the last run of each button ended, by name
+    paths             *pathWatcher         // Re-runs buttons when their watch_paths change, nil if unavailable
+    rerun             map[string]bool      // Buttons to run again when they finish, their watch_paths changed meanwhile
+    state             appState             // Remembered between sessions, such as how often buttons are used
+    tag               string               // Only buttons with this tag are listed, "" for all
+    runOutputs        map[string][]string  // Output of the last two runs of each button, oldest first
+    variables         map[string]string    // Output captured by buttons, by variable name, see captureOutput
+    compare           bool                 // The tabs of a side by side split scroll together, differences highlighted
+    compareGrown      map[int]bool         // Compared tabs with output the comparison doesn't show yet
+    compareTicking    bool                 // A compareRefreshMsg is on its way
+    confirmingQuit    bool                 // Asking whether to kill the running jobs and quit
+    stdinMode         bool                 // Typed lines go to the running command of the current tab, see inputJob
+    searching         bool                 // The input takes text to search the output for
+    exporting         bool                 // The input takes the file to export the output to
+    confirming        bool                 // The input takes the confirm phrase of the button at currentIndex
+    passwordJob       int                  // Job waiting for the sudo password typed in the input, 0 for none
+    regions           *screenRegions       // Where the panes were last drawn, for mouse clicks
+    lastClick         buttonClick          // The last click on a button, to notice double clicks
+    enabledErrors     map[string]string    // The last error of each button's enabled function, so it is only shown once
+    generators        []*generator         // Make buttons from the output of commands, see generator
+    marking           string               // "set" or "jump" while the mark keys wait for the mark's letter
+    recorder          *recorder            // Records the output to the --record file, nil unless given
+    accessible        bool                 // Drawn plainly for screen readers, see makeAccessible
+    replay            *replay              // Recorded session being stepped through, nil unless replaying
+    filterSeq         int                  // Numbers the list's filter runs, see buttonFilterMsg
+    filterShown       int                  // The filter run whose matches the list shows
+}
+
+// outputTab is one of the tabs above the output pane. Each keeps its own
+// output so commands started in different tabs don't mix.
+type outputTab struct {
+    title      string
+    layout     tabLayout
+    lines      []string // The output, the last line unfinished, nil when there is none
+    cleared    *string  // Output removed by the last clear, nil once undone
+    wrap       bool    // Long lines are wrapped to the viewport's width rather than cut off
+    search     string  // Text highlighted in the output, "" for none
+    pattern    *regexp.Regexp // search compiled, nil for none
+    matches    int     // How many times search appears in the output
+    matchLines []int   // Lines of the viewport's content with a match, for nextMatch
+    elevation  string  // Of the last command run in the tab, shown next to its title
+    icon       string  // Of the last command run in the tab, shown before its title
+    run        *job    // The last command run in the tab, nil for interactive ones
+    streams    streamView
+    folded     map[string]bool // Runs shown as just their header, by header
+    runs       []runStart      // Where each run starts in the viewport's content
+    atRun      string          // Header of the run the last jump went to
+    atLine     int             // Line of the viewport's content the last jump went to, see currentLine
+    marks      map[string]int  // Lines of the viewport's content by mark, see startMark
+    tail       int             // Line of the viewport's content the last line of output starts on
+    tailHits   int             // Matches before the last line of output
+    size       int             // Bytes of output in lines
+    spill      *spill          // Older output moved to disk, nil when none was
+    viewport   outputView
+}
+
+func newOutputTab(title string, vpDimensions, tiDimensions dimensions) outputTab {
+    vp := newOutputView(vpDimensions.width, vpDimensions.height-tiDimensions.height-4)
+    return outputTab{title: title, viewport: vp}
+}
+
+// appendOutput adds text to a tab's output and scrolls it to the end. Only
+// the lines it adds are fitted to the viewport, not the whole output again.
+func (m *model) appendOutput(tab int, text string) {
+    if m.accessible {
+        // Colors of the command's own would only be read out
+        text = ansiEscape.ReplaceAllString(text, "")
+    }
+    t := &m.tabs[tab]
+    from := len(t.lines) - 1
+    t.addText(text)
+    if m.tabMemory > 0 && t.size > m.tabMemory {
+        t.spillOutput(m.tabMemory)
+        from = -1 // Everything moved up, so all of it is redrawn
+    }
+    if m.recorder != nil {
+        m.recorder.output(text)
+    }
+    if m.isCompared(tab) {
+        // Diffing both tabs again for every batch of output would stall
+        // the UI, it is redrawn by compareTick instead
+        if m.compareGrown == nil {
+            m.compareGrown = make(map[int]bool)
+        }
+        m.compareGrown[tab] = true
+        return
+    }
+    t.showAdded(from)
+    t.viewport.GotoBottom()
+}
+
+// clearOutput empties the current tab, keeping what was there so
+// undoClear can bring it back. Older output moved to disk is deleted.
+func (m *model) clearOutput() {
+    t := &m.tabs[m.currentTab]
+    cleared := t.text()
+    t.cleared = &cleared
+    t.setText("")
+    t.dropSpill()
+    // Like the initial placeholder, the hint goes once output arrives
+    t.viewport.SetContent(fmt.Sprintf("Output cleared, press %s to undo", m.keys.UndoClear.Help().Key))
+}
+
+// undoClear puts back the output removed by the last clear of the current
+// tab, before anything written since.
+func (m *model) undoClear() {
+    t := &m.tabs[m.currentTab]
+    if t.cleared == nil {
+        return
+    }
+    t.setText(*t.cleared + t.text())
+    t.cleared = nil
+    t.showOutput()
+    t.viewport.GotoBottom()
+}
+
+// bellEndMsg takes BEL out of the view again, see ringBell.
+type bellEndMsg struct{}
+
+// flashEndMsg turns off the failure flash started with the same ID.
+type flashEndMsg struct {
+    id int
 }
 
 // Add a function to initialize tabs
@@ -79,15 +305,50 @@ func initTabs() []string {
 }
 
 type keyMap struct {
-    NextFocus key.Binding
-    PrevFocus key.Binding
-    Quit      key.Binding
-    Help      key.Binding
-    Execute   key.Binding
-    Filter    key.Binding
-    Refresh   key.Binding
-    NextTab   key.Binding // Key binding for switching to the next tab
-    PrevTab   key.Binding // Key binding for switching to the previous tab
+    NextFocus    key.Binding
+    PrevFocus    key.Binding
+    Quit         key.Binding
+    Help         key.Binding
+    Execute      key.Binding
+    Filter       key.Binding
+    Refresh      key.Binding
+    NextTab      key.Binding // Key binding for switching to the next tab
+    PrevTab      key.Binding // Key binding for switching to the previous tab
+    NextProfile  key.Binding
+    ToggleSplit  key.Binding // Show two tabs at once, side by side or stacked
+    SwitchPane   key.Binding // Move focus to the other tab of a split
+    GrowList     key.Binding
+    ShrinkList   key.Binding
+    GrowOutput   key.Binding // Taller output, shorter input
+    ShrinkOutput key.Binding
+    Zen          key.Binding // Show nothing but the output
+    Pager        key.Binding // Open the current tab's output in $PAGER
+    Pipe         key.Binding // Pipe the selected button's output into another command
+    Kube         key.Binding // Pick the Kubernetes context and namespace
+    Sort         key.Binding // Switch between config order and most used first
+    Pin          key.Binding // Keep the selected button at the top of the list
+    Tag          key.Binding // Only list the buttons with a tag
+    Clear        key.Binding // Empty the current tab's output
+    UndoClear    key.Binding // Bring back the output of the last clear
+    Wrap         key.Binding // Wrap long lines in the current tab
+    Diff         key.Binding // Compare the last two runs of the selected button
+    Compare      key.Binding // Compare the output of two tabs side by side
+    Signal       key.Binding // Send a signal to a running job
+    Stdin        key.Binding // Type into the running command's standard input
+    Editor       key.Binding // Compose a multi-line command or script
+    Search       key.Binding // Highlight text in the output
+    NextMatch    key.Binding
+    PrevMatch    key.Binding
+    Reload       key.Binding // Re-read the config and imports and run the generators again
+    Streams      key.Binding // Show only the standard output or error of the last run
+    Fold         key.Binding // Show only the header of the current run
+    PrevRun      key.Binding // Scroll to the start of the previous run
+    NextRun      key.Binding
+    SetMark      key.Binding // Remember the top of the output under a letter
+    JumpMark     key.Binding
+    Export       key.Binding // Save the output as HTML or markdown
+    ReplayNext   key.Binding // Show the next command of a replayed session
+    ReplayPrev   key.Binding
 }
 
 var keys = keyMap{
@@ -100,12 +361,12 @@ var keys = keyMap{
         key.WithHelp("ctrl+p", "prev focus"),
     ),
     Quit: key.NewBinding(
-        key.WithKeys("q"),
+        key.WithKeys("q", "ctrl+c"),
         key.WithHelp("q", "quit"),
     ),
     Help: key.NewBinding(
         key.WithKeys("?"),
-        key.WithHelp("?", "toggle help"),
+        key.WithHelp("?", "all keys"),
     ),
     Execute: key.NewBinding(
         key.WithKeys("enter"),
@@ -127,275 +388,1049 @@ var keys = keyMap{
         key.WithKeys("["),
         key.WithHelp("[", "previous tab"),
     ),
+    NextProfile: key.NewBinding(
+        key.WithKeys("P"),
+        key.WithHelp("P", "next profile"),
+    ),
+    ToggleSplit: key.NewBinding(
+        key.WithKeys("ctrl+s"),
+        key.WithHelp("ctrl+s", "split output"),
+    ),
+    SwitchPane: key.NewBinding(
+        key.WithKeys("ctrl+o"),
+        key.WithHelp("ctrl+o", "other pane"),
+    ),
+    GrowList: key.NewBinding(
+        key.WithKeys("ctrl+right"),
+        key.WithHelp("ctrl+→", "wider list"),
+    ),
+    ShrinkList: key.NewBinding(
+        key.WithKeys("ctrl+left"),
+        key.WithHelp("ctrl+←", "narrower list"),
+    ),
+    GrowOutput: key.NewBinding(
+        key.WithKeys("ctrl+down"),
+        key.WithHelp("ctrl+↓", "taller output"),
+    ),
+    ShrinkOutput: key.NewBinding(
+        key.WithKeys("ctrl+up"),
+        key.WithHelp("ctrl+↑", "shorter output"),
+    ),
+    Zen: key.NewBinding(
+        key.WithKeys("Z"),
+        key.WithHelp("Z", "zen mode"),
+    ),
+    Pager: key.NewBinding(
+        key.WithKeys("v"),
+        key.WithHelp("v", "open in pager"),
+    ),
+    Pipe: key.NewBinding(
+        key.WithKeys("|"),
+        key.WithHelp("|", "pipe into command"),
+    ),
+    Kube: key.NewBinding(
+        key.WithKeys("K"),
+        key.WithHelp("K", "kube context"),
+    ),
+    Sort: key.NewBinding(
+        key.WithKeys("S"),
+        key.WithHelp("S", "sort buttons"),
+    ),
+    Pin: key.NewBinding(
+        key.WithKeys("*"),
+        key.WithHelp("*", "pin button"),
+    ),
+    Tag: key.NewBinding(
+        key.WithKeys("#"),
+        key.WithHelp("#", "filter by tag"),
+    ),
+    Clear: key.NewBinding(
+        key.WithKeys("C"),
+        key.WithHelp("C", "clear output"),
+    ),
+    UndoClear: key.NewBinding(
+        key.WithKeys("U"),
+        key.WithHelp("U", "undo clear"),
+    ),
+    Wrap: key.NewBinding(
+        key.WithKeys("w"),
+        key.WithHelp("w", "wrap lines"),
+    ),
+    Diff: key.NewBinding(
+        key.WithKeys("D"),
+        key.WithHelp("D", "diff last runs"),
+    ),
+    Compare: key.NewBinding(
+        key.WithKeys("="),
+        key.WithHelp("=", "compare tabs"),
+    ),
+    Signal: key.NewBinding(
+        key.WithKeys("X"),
+        key.WithHelp("X", "signal job"),
+    ),
+    Stdin: key.NewBinding(
+        key.WithKeys("ctrl+t"),
+        key.WithHelp("ctrl+t", "stdin mode"),
+    ),
+    Editor: key.NewBinding(
+        key.WithKeys("ctrl+x"),
+        key.WithHelp("ctrl+x", "multi-line editor"),
+    ),
+    Search: key.NewBinding(
+        key.WithKeys("ctrl+f"),
+        key.WithHelp("ctrl+f", "search output"),
+    ),
+    NextMatch: key.NewBinding(
+        key.WithKeys("n"),
+        key.WithHelp("n", "next match"),
+    ),
+    PrevMatch: key.NewBinding(
+        key.WithKeys("N"),
+        key.WithHelp("N", "previous match"),
+    ),
+    Reload: key.NewBinding(
+        key.WithKeys("ctrl+r"),
+        key.WithHelp("ctrl+r", "reload buttons"),
+    ),
+    Streams: key.NewBinding(
+        key.WithKeys("e"),
+        key.WithHelp("e", "stdout/stderr"),
+    ),
+    Fold: key.NewBinding(
+        key.WithKeys("z"),
+        key.WithHelp("z", "fold run"),
+    ),
+    PrevRun: key.NewBinding(
+        key.WithKeys("{"),
+        key.WithHelp("{", "previous run"),
+    ),
+    NextRun: key.NewBinding(
+        key.WithKeys("}"),
+        key.WithHelp("}", "next run"),
+    ),
+    SetMark: key.NewBinding(
+        key.WithKeys("m"),
+        key.WithHelp("m", "set mark"),
+    ),
+    JumpMark: key.NewBinding(
+        key.WithKeys("'"),
+        key.WithHelp("'", "jump to mark"),
+    ),
+    Export: key.NewBinding(
+        key.WithKeys("E"),
+        key.WithHelp("E", "export output"),
+    ),
+    ReplayNext: key.NewBinding(
+        key.WithKeys(">"),
+        key.WithHelp(">", "next step"),
+    ),
+    ReplayPrev: key.NewBinding(
+        key.WithKeys("<"),
+        key.WithHelp("<", "previous step"),
+    ),
 }
 
-func (k keyMap) ShortHelp() []key.Binding {
-    return []key.Binding{k.NextFocus, k.PrevFocus, k.Execute, k.Filter, k.Refresh, k.Help, k.Quit, k.NextTab, k.PrevTab}
+// bindings returns pointers to every binding in k, keyed by the name used
+// to remap it in the config's keys section.
+func (k *keyMap) bindings() map[string]*key.Binding {
+    return map[string]*key.Binding{
+        "next_focus":    &k.NextFocus,
+        "prev_focus":    &k.PrevFocus,
+        "quit":          &k.Quit,
+        "help":          &k.Help,
+        "execute":       &k.Execute,
+        "filter":        &k.Filter,
+        "refresh":       &k.Refresh,
+        "next_tab":      &k.NextTab,
+        "prev_tab":      &k.PrevTab,
+        "next_profile":  &k.NextProfile,
+        "toggle_split":  &k.ToggleSplit,
+        "switch_pane":   &k.SwitchPane,
+        "grow_list":     &k.GrowList,
+        "shrink_list":   &k.ShrinkList,
+        "grow_output":   &k.GrowOutput,
+        "shrink_output": &k.ShrinkOutput,
+        "zen":           &k.Zen,
+        "pager":         &k.Pager,
+        "pipe":          &k.Pipe,
+        "kube":          &k.Kube,
+        "sort":          &k.Sort,
+        "pin":           &k.Pin,
+        "tag":           &k.Tag,
+        "clear":         &k.Clear,
+        "undo_clear":    &k.UndoClear,
+        "wrap":          &k.Wrap,
+        "diff":          &k.Diff,
+        "compare":       &k.Compare,
+        "signal":        &k.Signal,
+        "stdin":         &k.Stdin,
+        "editor":        &k.Editor,
+        "search":        &k.Search,
+        "next_match":    &k.NextMatch,
+        "prev_match":    &k.PrevMatch,
+        "reload":        &k.Reload,
+        "streams":       &k.Streams,
+        "fold":          &k.Fold,
+        "prev_run":      &k.PrevRun,
+        "next_run":      &k.NextRun,
+        "set_mark":      &k.SetMark,
+        "jump_mark":     &k.JumpMark,
+        "export":        &k.Export,
+        "replay_next":   &k.ReplayNext,
+        "replay_prev":   &k.ReplayPrev,
+    }
 }
 
-func (k keyMap) FullHelp() [][]key.Binding {
-    return [][]key.Binding{
-        {k.NextFocus, k.PrevFocus, k.Execute, k.Filter},
-        {k.Refresh, k.Help, k.Quit},
-        {k.NextTab, k.PrevTab},
+// withOverrides returns a copy of k with the bindings named in overrides
+// rebound to the given keys.
+func (k keyMap) withOverrides(overrides map[string][]string) keyMap {
+    bindings := k.bindings()
+    for name, keys := range overrides {
+        if b, ok := bindings[name]; ok {
+            b.SetKeys(keys...)
+            b.SetHelp(strings.Join(keys, "/"), b.Help().Desc)
+        }
     }
+    return k
 }
 
-func loadConfig() ([]command, dimensions, dimensions, dimensions, []string, error) {
-    L := lua.NewState()
-    defer L.Close()
+func initialModel(cfg config) model {
+    applyStyles(cfg.theme, cfg.styles, cfg.borders)
+    icons = cfg.icons
+    commands := cfg.commands
+    vpDimensions, listDimensions, tiDimensions := cfg.vpDimensions, cfg.listDimensions, cfg.tiDimensions
+
+    l := list.New(commandItems(commands, appState{}, ""), customDelegate{}, listDimensions.width, listDimensions.height)
+    l.Title = "Buttons"
+    l.SetShowStatusBar(false)
+    l.SetFilteringEnabled(true)
+    l.SetShowHelp(false)
 
-    if err := L.DoFile("config.lua"); err != nil {
-        return nil, dimensions{}, dimensions{}, dimensions{}, nil, err
+    tabs := make([]outputTab, len(cfg.tabs))
+    for i, t := range cfg.tabs {
+        tabs[i] = newOutputTab(t.title, vpDimensions, tiDimensions)
+        tabs[i].layout = t.layout
+        tabs[i].wrap = t.wrap
     }
+    tabs[0].viewport.SetContent("Output will be displayed here...")
 
-    luaTable := L.Get(-1).(*lua.LTable)
-    commands := extractCommands(luaTable.RawGetString("buttons").(*lua.LTable))
-    vpDimensions := extractDimensions(luaTable.RawGetString("viewport").(*lua.LTable))
-    listDimensions := extractDimensions(luaTable.RawGetString("list").(*lua.LTable))
-    tiDimensions := dimensions{width: int(luaTable.RawGetString("textinput").(*lua.LTable).RawGetString("width").(lua.LNumber)), height: 1}
-    completions := extractCompletions(luaTable.RawGetString("completions").(*lua.LTable))
+    ti := textinput.New()
+    ti.Placeholder = "Type a command..."
+    ti.Focus()
+    ti.Width = tiDimensions.width
 
-    return commands, vpDimensions, listDimensions, tiDimensions, completions, nil
-}
+    h := help.New()
+    k := keys.withOverrides(cfg.keys)
 
-func extractCommands(buttonsTable *lua.LTable) []command {
-    var commands []command
-    buttonsTable.ForEach(func(_, value lua.LValue) {
-        buttonTable := value.(*lua.LTable)
-        name := buttonTable.RawGetString("name").String()
-        cmd := extractCmd(buttonTable.RawGetString("cmd").(*lua.LTable))
-        prompt := buttonTable.RawGetString("prompt").(lua.LBool)
+    s := spinner.New()
+    s.Spinner = icons.spinner
 
-        commands = append(commands, command{name, cmd, bool(prompt)})
-    })
-    return commands
+    m := model{
+        list:              l,
+        input:             ti,
+        focus:             focusList,
+        sharedCommands:    commands,
+        profiles:          cfg.profiles,
+        vpDimensions:      vpDimensions,
+        listDimensions:    listDimensions,
+        tiDimensions:      tiDimensions,
+        sharedCompletions: cfg.completions,
+        aliases:           cfg.aliases,
+        targets:           cfg.targets,
+        picked:            make(map[string]string),
+        lastRuns:          make(map[string]runResult),
+        rerun:             make(map[string]bool),
+        runOutputs:        make(map[string][]string),
+        variables:         make(map[string]string),
+        enabledErrors:     make(map[string]string),
+        regions:           &screenRegions{},
+        tmuxTarget:        cfg.tmuxTarget,
+        currentIndex:      -1,
+        help:              h,
+        keys:              k,
+        prompInput:        false,
+        currentTab:        0,
+        tabs:              tabs,
+        spinner:           s,
+        editor:            newEditor(),
+        bellOnFailure:     cfg.bellOnFailure,
+        flashOnFailure:    cfg.flashOnFailure,
+        glob:              cfg.glob,
+        vimNavigation:     cfg.vimNavigation,
+        viInput:           cfg.viInput,
+        tabMemory:         cfg.tabMemory << 20,
+        script:            cfg.script,
+        generators:        cfg.generators,
+    }
+    m.restyle(cfg.theme)
+    m.setReducedMotion(cfg.reducedMotion)
+    state, err := loadState()
+    m.state = state
+    if err != nil {
+        m.appendOutput(0, fmt.Sprintf("Error loading state: %v\n", err))
+    }
+    m.resizeTabs()
+    profileCmd := m.useProfile(cfg.profile)
+    // Apply whatever the config script queued while it was loading
+    m.initCmd = tea.Batch(profileCmd, m.applyScriptActions(), m.generate())
+    return m
 }
 
-func extractCmd(cmdTable *lua.LTable) []string {
-    var cmd []string
-    cmdTable.ForEach(func(_, cmdValue lua.LValue) {
-        cmd = append(cmd, cmdValue.String())
-    })
-    return cmd
-}
+// manyPages is how many pages of buttons the list shows as "3/40" rather
+// than as a dot per page.
+const manyPages = 10
 
-func extractDimensions(dimTable *lua.LTable) dimensions {
-    return dimensions{
-        width:  int(dimTable.RawGetString("width").(lua.LNumber)),
-        height: int(dimTable.RawGetString("height").(lua.LNumber)),
+// commandItems builds the list entries for commands, leaving out the ones
+// without tag unless it is "", and marking the ones pinned in state. Pinned
+// buttons are expected to come first.
+func commandItems(commands []command, state appState, tag string) []list.Item {
+    var items []list.Item
+    for i, cmd := range commands {
+        if tag != "" && !cmd.hasTag(tag) {
+            continue
+        }
+        items = append(items, listItem{
+            title:       cmd.name,
+            group:       cmd.group,
+            description: cmd.description,
+            elevation:   cmd.elevation(),
+            icon:        cmd.shownIcon(),
+            tags:        cmd.tags,
+            index:       i,
+            pinned:      state.isPinned(cmd.name),
+        })
+    }
+    for i := range items {
+        item := items[i].(listItem)
+        item.lastPinned = item.pinned && (i+1 == len(items) || !items[i+1].(listItem).pinned)
+        item.filterValue = strings.Join(append([]string{item.Title()}, item.tags...), " ")
+        items[i] = item
     }
+    return items
 }
 
-func extractCompletions(completionsTable *lua.LTable) []string {
-    var completions []string
-    completionsTable.ForEach(func(_, value lua.LValue) {
-        completions = append(completions, value.String())
-    })
-    return completions
+// selectedIndex returns the index in m.commands of the button selected in
+// the list, and false when the list is empty.
+func (m *model) selectedIndex() (int, bool) {
+    item, ok := m.list.SelectedItem().(listItem)
+    if !ok || item.index >= len(m.commands) {
+        return 0, false
+    }
+    return item.index, true
 }
 
-func initialModel(commands []command, vpDimensions, listDimensions, tiDimensions dimensions, completions []string) model {
-    items := make([]list.Item, len(commands))
-    for i, cmd := range commands {
-        items[i] = listItem{cmd.name}
+// runSelected runs the button selected in the list, asking for its argument
+// first when it takes one.
+func (m *model) runSelected() tea.Cmd {
+    idx, ok := m.selectedIndex()
+    if !ok {
+        return nil
     }
+    return m.startButton(idx)
+}
 
-    l := list.New(items, customDelegate{}, listDimensions.width, listDimensions.height)
-    l.Title = "Buttons"
-    l.SetShowStatusBar(false)
-    l.SetFilteringEnabled(true)
-    l.SetShowHelp(false)
-
-    mainViewport := viewport.New(vpDimensions.width, vpDimensions.height-tiDimensions.height-4)
-    mainViewport.SetContent("Output will be displayed here...")
-    mainViewport.MouseWheelEnabled = true
+// runAt runs the button at idx, asking for its argument first if it
+// prompts. The stand-in for a generator's buttons runs the generator.
+func (m *model) runAt(idx int) tea.Cmd {
+    cmd := m.commands[idx]
+    if g := cmd.generator; g != nil {
+        if g.running {
+            return nil
+        }
+        return tea.Batch(runGenerator(g), m.sortCommands())
+    }
+    if cmd.prompt {
+        // Command requires input, prompt the user
+        return m.askArgument(idx)
+    }
+    m.noteUse(cmd.name)
+    return tea.Batch(m.runButton(cmd), m.sortCommands())
+}
 
-    otherViewport := viewport.New(vpDimensions.width, vpDimensions.height-tiDimensions.height-4)
-    otherViewport.SetContent("")
+// applyConfig swaps in a reloaded config, keeping the output and any
+// running jobs intact.
+func (m *model) applyConfig(cfg config) tea.Cmd {
+    m.sharedCommands = cfg.commands
+    m.sharedCompletions = cfg.completions
+    // Stay in the active profile if it still exists
+    profile := cfg.profile
+    if m.profile >= 0 {
+        if i := findProfile(cfg.profiles, m.profiles[m.profile].name); i >= 0 {
+            profile = i
+        }
+    }
+    m.profiles = cfg.profiles
+    m.keys = keys.withOverrides(cfg.keys)
+    applyStyles(cfg.theme, cfg.styles, cfg.borders)
+    m.restyle(cfg.theme)
+    icons = cfg.icons
+    m.spinner.Spinner = icons.spinner
+    m.setReducedMotion(cfg.reducedMotion)
+    if m.accessible {
+        m.makeAccessible()
+    }
+    m.aliases = cfg.aliases
+    m.targets = cfg.targets
+    m.tmuxTarget = cfg.tmuxTarget
+    m.bellOnFailure = cfg.bellOnFailure
+    m.flashOnFailure = cfg.flashOnFailure
+    m.glob = cfg.glob
+    m.vimNavigation = cfg.vimNavigation
+    if m.viInput = cfg.viInput; !m.viInput {
+        m.viNormal, m.viPending = false, ""
+    }
+    m.tabMemory = cfg.tabMemory << 20
 
-    vp := []viewport.Model{mainViewport, otherViewport, otherViewport} // Add more viewports as needed
+    m.vpDimensions, m.listDimensions, m.tiDimensions = cfg.vpDimensions, cfg.listDimensions, cfg.tiDimensions
+    m.list.SetSize(m.listDimensions.width, m.listDimensions.height)
+    m.input.Width = m.tiDimensions.width
 
-    ti := textinput.New()
-    ti.Placeholder = "Type a command..."
-    ti.Focus()
-    ti.Width = tiDimensions.width
+    // Keep tabs that still exist, by position, so their output survives
+    for len(m.tabs) < len(cfg.tabs) {
+        m.tabs = append(m.tabs, newOutputTab("", m.vpDimensions, m.tiDimensions))
+    }
+    for i := len(cfg.tabs); i < len(m.tabs); i++ {
+        m.tabs[i].dropSpill()
+    }
+    m.tabs = m.tabs[:len(cfg.tabs)]
+    for i := range m.tabs {
+        m.tabs[i].title = cfg.tabs[i].title
+        m.tabs[i].layout = cfg.tabs[i].layout
+        m.tabs[i].wrap = cfg.tabs[i].wrap
+    }
+    // Commands still running in a tab that is gone carry on in the last one
+    last := len(m.tabs) - 1
+    for _, j := range m.jobs {
+        if j.tab > last {
+            j.tab = last
+            m.appendOutput(last, statusStyle.Render(j.cmd.name+" moved here, the tab it ran in was removed")+"\n")
+        }
+    }
+    m.resizeTabs()
+    if m.currentTab >= len(m.tabs) {
+        m.currentTab = len(m.tabs) - 1
+    }
 
-    h := help.New()
-    k := keys
-
-    return model{
-        list:           l,
-        viewports:      vp,
-        input:          ti,
-        focus:          focusList,
-        commands:       commands,
-        showHelp:       true,
-        vpDimensions:   vpDimensions,
-        listDimensions: listDimensions,
-        tiDimensions:   tiDimensions,
-        completions:    completions,
-        currentIndex:   -1,
-        help:           h,
-        keys:           k,
-        prompInput:     false,
-        currentTab:     0,
-        tabs:           initTabs(),
+    if m.script != nil {
+        m.script.close()
     }
+    m.script = cfg.script
+    m.generators = cfg.generators
+
+    return tea.Batch(m.useProfile(profile), m.applyScriptActions(), m.generate())
 }
 
 func (m model) Init() tea.Cmd {
-    return nil
+    cmds := []tea.Cmd{m.initCmd}
+    if m.watcher != nil {
+        cmds = append(cmds, m.watcher.wait())
+    }
+    if m.control != nil {
+        cmds = append(cmds, m.control.wait())
+    }
+    if m.paths != nil {
+        cmds = append(cmds, m.paths.wait())
+    }
+    return tea.Batch(cmds...)
 }
 
+// Update handles msg, then schedules redrawing a comparison that output
+// arrived for.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+    next, cmd := m.announced(msg)
+    n, ok := next.(model)
+    if !ok {
+        return next, cmd
+    }
+    return n, tea.Batch(cmd, n.compareTick())
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
     var cmds []tea.Cmd
 
     switch msg := msg.(type) {
     case tea.KeyMsg:
+        if m.confirmingQuit {
+            return m, m.confirmQuit(msg)
+        }
+        if m.marking != "" {
+            m.finishMark(msg)
+            return m, nil
+        }
+        if m.cheatSheet {
+            // Any other key is ignored until the cheat sheet is closed
+            if key.Matches(msg, m.keys.Help) || msg.String() == "esc" {
+                m.cheatSheet = false
+            }
+            return m, nil
+        }
+        if m.editing {
+            return m, m.editorKey(msg)
+        }
+        if m.vimNavigation && m.focus == focusViewport && m.vimMotion(msg) {
+            return m, nil
+        }
+        if m.viInput && m.focus == focusInput && m.viKey(msg) {
+            return m, nil
+        }
         switch {
+        case m.focus == focusInput && m.inputTakes(msg):
+            // Typed or edited with below
         case key.Matches(msg, m.keys.NextFocus):
-            m.focus = (m.focus + 1) % 3
+            m.cycleFocus(1)
         case key.Matches(msg, m.keys.PrevFocus):
-            m.focus = (m.focus + 2) % 3
+            m.cycleFocus(-1)
         case key.Matches(msg, m.keys.Quit):
-            return m, tea.Quit
-        case key.Matches(msg, m.keys.Help):
-            m.showHelp = !m.showHelp
+            return m, m.quit()
+        case key.Matches(msg, m.keys.Help) && m.focus != focusInput && !m.list.SettingFilter():
+            m.cheatSheet = true
         case key.Matches(msg, m.keys.Refresh):
             if m.focus == focusViewport {
-                m.viewports[m.currentTab].SetContent(m.output)
-                m.viewports[m.currentTab].GotoBottom()
+                m.tabs[m.currentTab].showOutput()
+                m.tabs[m.currentTab].viewport.GotoBottom()
             }
         case key.Matches(msg, m.keys.NextTab):
-            m.currentTab = (m.currentTab + 1) % len(m.viewports)
+            m.selectTab((m.currentTab + 1) % len(m.tabs))
         case key.Matches(msg, m.keys.PrevTab):
-            m.currentTab = (m.currentTab - 1 + len(m.viewports)) % len(m.viewports)
+            m.selectTab((m.currentTab - 1 + len(m.tabs)) % len(m.tabs))
+        case key.Matches(msg, m.keys.ToggleSplit):
+            m.toggleSplit()
+        case key.Matches(msg, m.keys.SwitchPane):
+            m.switchPane()
+        case key.Matches(msg, m.keys.Compare) && m.focus != focusInput && !m.list.SettingFilter():
+            m.toggleCompare()
+        // The input uses ctrl+arrows to move by word
+        case key.Matches(msg, m.keys.GrowList) && m.focus != focusInput:
+            m.resizePanes(2, 0)
+        case key.Matches(msg, m.keys.ShrinkList) && m.focus != focusInput:
+            m.resizePanes(-2, 0)
+        case key.Matches(msg, m.keys.GrowOutput) && m.focus != focusInput:
+            m.resizePanes(0, 1)
+        case key.Matches(msg, m.keys.ShrinkOutput) && m.focus != focusInput:
+            m.resizePanes(0, -1)
+        case key.Matches(msg, m.keys.Zen) && m.focus != focusInput && !m.list.SettingFilter():
+            m.toggleZen()
+        case key.Matches(msg, m.keys.Pager) && m.focus != focusInput && !m.list.SettingFilter():
+            return m, m.openPager()
+        case key.Matches(msg, m.keys.NextProfile) && m.focus != focusInput && !m.list.SettingFilter():
+            cmds = append(cmds, m.nextProfile())
+        case key.Matches(msg, m.keys.Kube) && m.focus != focusInput && !m.list.SettingFilter():
+            return m, m.switchKube()
+        case key.Matches(msg, m.keys.Sort) && m.focus != focusInput && !m.list.SettingFilter():
+            cmds = append(cmds, m.nextSort())
+        case key.Matches(msg, m.keys.Pin) && m.focus == focusList && !m.list.SettingFilter():
+            cmds = append(cmds, m.togglePin())
+        case key.Matches(msg, m.keys.Tag) && m.focus != focusInput && !m.list.SettingFilter():
+            return m, m.pickTag()
+        case key.Matches(msg, m.keys.Clear) && m.focus != focusInput && !m.list.SettingFilter():
+            m.clearOutput()
+        case key.Matches(msg, m.keys.UndoClear) && m.focus != focusInput && !m.list.SettingFilter():
+            m.undoClear()
+        case key.Matches(msg, m.keys.Wrap) && m.focus != focusInput && !m.list.SettingFilter():
+            m.toggleWrap()
+        case key.Matches(msg, m.keys.Streams) && m.focus != focusInput && !m.list.SettingFilter():
+            m.toggleStreams()
+        case key.Matches(msg, m.keys.Fold) && m.focus != focusInput && !m.list.SettingFilter():
+            m.toggleFold()
+        case key.Matches(msg, m.keys.PrevRun) && m.focus != focusInput && !m.list.SettingFilter():
+            m.jumpRun(-1)
+        case key.Matches(msg, m.keys.NextRun) && m.focus != focusInput && !m.list.SettingFilter():
+            m.jumpRun(1)
+        case key.Matches(msg, m.keys.SetMark) && m.focus != focusInput && !m.list.SettingFilter():
+            m.startMark("set")
+            return m, nil
+        case key.Matches(msg, m.keys.JumpMark) && m.focus != focusInput && !m.list.SettingFilter():
+            m.startMark("jump")
+            return m, nil
+        case key.Matches(msg, m.keys.Diff) && m.focus != focusInput && !m.list.SettingFilter():
+            m.diffRuns()
+        case key.Matches(msg, m.keys.Reload):
+            return m, m.reloadButtons()
+        case key.Matches(msg, m.keys.Signal) && m.focus != focusInput && !m.list.SettingFilter():
+            return m, m.signalMenu()
+        case key.Matches(msg, m.keys.Stdin):
+            m.toggleStdin()
+            return m, nil
+        case key.Matches(msg, m.keys.Editor) && !m.prompInput && m.pipeSource == nil && !m.searching && !m.exporting && !m.confirming && m.passwordJob == 0:
+            return m, m.openEditor()
+        case key.Matches(msg, m.keys.Search) && m.focus != focusInput:
+            m.startSearch()
+            return m, nil
+        case key.Matches(msg, m.keys.Export) && m.focus != focusInput && !m.list.SettingFilter():
+            m.startExport()
+            return m, nil
+        case key.Matches(msg, m.keys.ReplayNext) && m.replay != nil && m.focus != focusInput && !m.list.SettingFilter():
+            m.stepReplay(1)
+            return m, nil
+        case key.Matches(msg, m.keys.ReplayPrev) && m.replay != nil && m.focus != focusInput && !m.list.SettingFilter():
+            m.stepReplay(-1)
+            return m, nil
+        case key.Matches(msg, m.keys.NextMatch) && m.focus != focusInput && m.tabs[m.currentTab].search != "" && !m.list.SettingFilter():
+            m.nextMatch(1)
+            return m, nil
+        case key.Matches(msg, m.keys.PrevMatch) && m.focus != focusInput && m.tabs[m.currentTab].search != "" && !m.list.SettingFilter():
+            m.nextMatch(-1)
+            return m, nil
         }
 
         if m.focus == focusList && key.Matches(msg, m.keys.Execute) {
-            idx := m.list.Index()
-            if idx >= 0 && idx < len(m.commands) {
-                cmd := m.commands[idx]
-                if cmd.prompt {
-                    // Command requires input, prompt the user
-                    m.input.SetValue("")
-                    m.input.Focus()
-                    m.focus = focusInput
-                    m.prompInput = true
-                    m.currentIndex = idx
-                    return m, nil
+            cmd := m.runSelected()
+            if m.prompInput || m.confirming {
+                // Waiting for the button's argument or confirm phrase. The
+                // list still needs the enter if it ends typing a filter
+                if m.list.SettingFilter() {
+                    var listCmd tea.Cmd
+                    m.list, listCmd = m.list.Update(msg)
+                    return m, m.numberFilters(listCmd)
                 }
-                m.runCommand(cmd)
+                return m, nil
             }
+            cmds = append(cmds, cmd)
+        } else if m.focus == focusList && key.Matches(msg, m.keys.Pipe) && !m.list.SettingFilter() {
+            m.startPipe()
+            return m, nil
         } else if m.focus == focusInput {
             switch msg.String() {
             case "enter":
                 inputValue := m.input.Value()
+                if m.searching {
+                    m.resetInput()
+                    m.focus = focusList
+                    m.search(inputValue)
+                    return m, nil
+                }
+                if m.exporting {
+                    m.resetInput()
+                    m.focus = focusList
+                    if inputValue != "" {
+                        m.export(inputValue)
+                    }
+                    return m, nil
+                }
+                if m.passwordJob != 0 {
+                    m.sendPassword(inputValue)
+                    return m, nil
+                }
+                if m.confirming {
+                    return m, m.checkPhrase(inputValue)
+                }
+                if j := m.inputJob(m.currentTab); j != nil && m.stdinMode && !m.prompInput && m.pipeSource == nil {
+                    // Empty lines count too, and the input stays for the next
+                    if err := j.sendInput(inputValue); err != nil {
+                        m.appendOutput(m.currentTab, fmt.Sprintf("Error sending input: %v\n", err))
+                    }
+                    m.input.SetValue("")
+                    return m, nil
+                }
+                var words []string
+                if inputValue != "" && !m.prompInput && (m.pipeSource != nil || m.inputJob(m.currentTab) == nil) {
+                    // Typed commands are split like a shell would
+                    var err error
+                    if words, err = shellWords(inputValue); err != nil {
+                        m.promptError = err.Error()
+                        return m, nil
+                    }
+                }
                 if inputValue != "" {
                     if m.prompInput == true {
                         // Get cmd from list to append to it
                         idx := m.currentIndex
                         if idx >= 0 && idx < len(m.commands) {
-                            cmd := m.commands[idx]
-                            fullCmd := append(cmd.cmd, inputValue)
-                            fullCommand := command{
-                                name:   cmd.name,
-                                cmd:    fullCmd,
-                                prompt: false,
+                            fullCommand := m.commands[idx]
+                            if problem := m.checkArgument(fullCommand.promptSpec, inputValue); problem != "" {
+                                // Keep the prompt open so the argument can be fixed
+                                m.promptError = problem
+                                return m, nil
                             }
-                            m.runCommand(fullCommand)
+                            fullCommand.prompt = false
+                            m.noteUse(fullCommand.name)
+                            cmds = append(cmds, m.runButton(fullCommand, fullCommand.promptSpec.args(inputValue)...))
                         }
                         m.prompInput = false
+                        cmds = append(cmds, m.sortCommands())
+                    } else if m.pipeSource != nil {
+                        source := *m.pipeSource
+                        source.pipe = m.expandAlias(words)
+                        source.name += " | " + inputValue
+                        cmds = append(cmds, m.runButton(source))
+                    } else if j := m.inputJob(m.currentTab); j != nil {
+                        // Typed lines go to the command waiting on its terminal
+                        if err := j.sendInput(inputValue); err != nil {
+                            m.appendOutput(m.currentTab, fmt.Sprintf("Error sending input: %v\n", err))
+                        }
                     } else {
                         // Create command structure for arbitrary command
                         cmd := command{
                             name:   inputValue,
-                            cmd:    strings.Fields(inputValue),
+                            cmd:    m.expandAlias(words),
                             prompt: false,
                         }
-                        m.runCommand(cmd)
+                        cmds = append(cmds, m.fillPlaceholders(cmd, runWith(nil)))
                     }
                 }
-                m.input.SetValue("")
+                m.resetInput()
                 m.focus = focusList
+            case "ctrl+d":
+                if m.stdinMode {
+                    m.closeStdin()
+                }
+            case "+", "-", "up", "down":
+                if dir := m.stepDirection(msg.String()); dir != 0 {
+                    m.stepArgument(dir)
+                    return m, nil
+                }
+                m.suggestions = nil
+                m.promptError = ""
             case "tab":
-                if len(m.completions) > 0 {
-                    m.currentIndex = (m.currentIndex + 1) % len(m.completions)
-                    m.input.SetValue(m.completions[m.currentIndex])
+                if m.passwordJob == 0 {
+                    m.nextCompletion()
                 }
+            default:
+                m.suggestions = nil
+                m.promptError = ""
             }
         } else if m.focus == focusViewport && key.Matches(msg, m.keys.Filter) {
             m.filterOutput()
         }
+    case buttonFilterMsg:
+        return m, m.showFilter(msg)
+    case compareRefreshMsg:
+        m.refreshComparison()
+        return m, nil
+    case commandOutputMsg:
+        j := m.job(msg.job)
+        if j == nil {
+            return m, nil
+        }
+        if msg.notice != "" {
+            m.appendOutput(j.tab, statusStyle.Render(msg.notice)+"\n")
+            return m, j.wait()
+        }
+        var lines []string
+        var shown strings.Builder
+        for _, line := range msg.lines {
+            if m.askPassword(j, line) {
+                continue
+            }
+            lines = append(lines, line)
+            j.record(line, msg.stderr)
+            if !j.cmd.capturesOutput() {
+                // Otherwise it is held back until the transform or renderer can see all of it
+                shown.WriteString(outputLine(j.cmd.renderLine(line), msg.stderr) + "\n")
+            }
+        }
+        if shown.Len() > 0 {
+            m.appendOutput(j.tab, shown.String())
+        }
+        cmds = append(cmds, j.wait())
+        for _, line := range lines {
+            cmds = append(cmds, m.runHook("on_output", lua.LString(line), lua.LString(j.cmd.name)))
+            if percent, ok := j.parseProgress(line); ok {
+                m.progressJob = j.id
+                cmds = append(cmds, m.setProgress(percent))
+            }
+        }
+        return m, tea.Batch(cmds...)
+    case commandFinishedMsg:
+        j := m.job(msg.job)
+        if j == nil {
+            return m, nil
+        }
+        for i := range m.jobs {
+            if m.jobs[i] == j {
+                m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+                break
+            }
+        }
+        if m.progressJob == j.id {
+            m.progressJob = 0
+        }
+        if m.passwordJob == j.id {
+            // Gave up asking, or never needed the answer
+            m.resetInput()
+            m.focus = focusList
+        }
+        if m.stdinMode && m.inputJob(m.currentTab) == nil {
+            // Lines typed from now on would start commands instead
+            m.stdinMode = false
+        }
+        if m.confirmingQuit && len(m.jobs) == 0 {
+            // Nothing left to ask about
+            cmds = append(cmds, tea.Quit)
+        }
+        if j.cmd.capturesOutput() {
+            m.appendOutput(j.tab, m.renderOutput(j.cmd, j.captured.String(), m.tabs[j.tab].viewport.Width))
+        }
+        elapsed := time.Since(j.started)
+        footer := runFooter(j.cmd.name, msg.err, elapsed)
+        m.appendOutput(j.tab, footer)
+        cmds = append(cmds, m.announce(strings.TrimSuffix(footer, "\n")))
+        if msg.err != nil {
+            cmds = append(cmds, m.alertFailure(j.tab))
+        } else if j.cmd.capture.name != "" {
+            m.captureOutput(j)
+        }
+        cmds = append(cmds, m.runHook("on_exit", lua.LNumber(exitCode(msg.err)), lua.LString(j.cmd.name)))
+        m.recordRun(j.cmd.name, msg.err, elapsed)
+        m.rememberRun(j.cmd.name, j.captured.String())
+        if j.shouldNotify(elapsed) {
+            cmds = append(cmds, notifyFinished(j.cmd.name, msg.err, elapsed))
+        }
+        if m.rerun[j.cmd.name] {
+            delete(m.rerun, j.cmd.name)
+            if cmd, ok := m.findButton(j.cmd.name); ok {
+                cmds = append(cmds, m.runButton(cmd))
+            }
+        }
+        return m, tea.Batch(cmds...)
+    case generatedMsg:
+        return m, m.applyGenerated(msg)
+    case configReloadedMsg:
+        if msg.err != nil {
+            m.appendOutput(m.currentTab, fmt.Sprintf("Error reloading config: %v\n", msg.err))
+        } else {
+            m.appendOutput(m.currentTab, fmt.Sprintf("Reloaded config from %s\n", m.watcher.path))
+            cmds = append(cmds, m.applyConfig(msg.cfg))
+        }
+        cmds = append(cmds, m.watcher.wait())
+        return m, tea.Batch(cmds...)
+    case pathsChangedMsg:
+        return m, tea.Batch(m.pathsChanged(msg), m.paths.wait())
+    case controlRequestMsg:
+        return m, tea.Batch(m.handleControl(msg), m.control.wait())
+    case interactiveFinishedMsg:
+        return m, m.interactiveFinished(msg)
+    case placeholderPickedMsg:
+        return m, m.placeholderPicked(msg)
+    case pagerFinishedMsg:
+        if msg.err != nil {
+            m.appendOutput(m.currentTab, fmt.Sprintf("Error running pager: %v\n", msg.err))
+        }
+        return m, nil
+    case flashEndMsg:
+        if msg.id == m.flashID {
+            m.flashing = false
+        }
+        return m, nil
+    case bellEndMsg:
+        m.ringing = false
+        return m, nil
+    case progress.FrameMsg:
+        progressModel, progressCmd := m.progress.Update(msg)
+        m.progress = progressModel.(progress.Model)
+        return m, progressCmd
+    case spinner.TickMsg:
+        // Let the spinner stop ticking once nothing is running
+        if len(m.jobs) == 0 {
+            return m, nil
+        }
+        var spinnerCmd tea.Cmd
+        m.spinner, spinnerCmd = m.spinner.Update(msg)
+        return m, spinnerCmd
+    case tea.WindowSizeMsg:
+        m.width, m.height = msg.Width, msg.Height
+        m.resizeTabs()
+        if m.editing {
+            m.sizeEditor()
+        }
+        return m, nil
     case tea.MouseMsg:
+        if tea.MouseEvent(msg).IsWheel() {
+            if cmd, ok := m.wheel(msg); ok {
+                return m, cmd
+            }
+        }
         switch msg.Type {
         case tea.MouseLeft:
-            if m.focus == focusList {
-                // If clicking on the list, change focus to the viewport
-                m.focus = focusViewport
-            } else if m.focus == focusViewport {
-                // If clicking on the viewport, change focus to the input
-                m.focus = focusInput
-                m.input.Focus()
-            } else if m.focus == focusInput {
-                // If clicking on the input, change focus to the list
-                m.focus = focusList
-            }
+            cmds = append(cmds, m.click(msg.X, msg.Y))
+        case tea.MouseMiddle:
+            cmds = append(cmds, m.middleClick(msg.X, msg.Y))
         }
     }
 
-    if m.focus == focusList {
+    m.fixFocus()
+
+    if m.editing {
+        // Keys went to it already, this is its cursor blinking
+        var editorCmd tea.Cmd
+        m.editor, editorCmd = m.editor.Update(msg)
+        cmds = append(cmds, editorCmd)
+    } else if m.focus == focusList {
         var listCmd tea.Cmd
         m.list, listCmd = m.list.Update(msg)
-        cmds = append(cmds, listCmd)
+        cmds = append(cmds, m.numberFilters(listCmd))
     } else if m.focus == focusInput {
         var inputCmd tea.Cmd
         m.input, inputCmd = m.input.Update(msg)
         cmds = append(cmds, inputCmd)
     } else {
         var viewportCmd tea.Cmd
-        m.viewports[m.currentTab], viewportCmd = m.viewports[m.currentTab].Update(msg)
+        m.tabs[m.currentTab].viewport, viewportCmd = m.tabs[m.currentTab].viewport.Update(msg)
+        m.syncScroll()
         cmds = append(cmds, viewportCmd)
     }
 
     return m, tea.Batch(cmds...)
 }
 
-func (m *model) runCommand(cmd command) {
+// alertFailure rings the bell and/or starts flashing tab, depending on config.
+func (m *model) alertFailure(tab int) tea.Cmd {
+    var cmds []tea.Cmd
+    if m.bellOnFailure {
+        cmds = append(cmds, m.ringBell())
+    }
+    if m.flashOnFailure && !m.reducedMotion {
+        m.flashID++
+        m.flashTab = tab
+        m.flashing = true
+        id := m.flashID
+        cmds = append(cmds, tea.Tick(600*time.Millisecond, func(time.Time) tea.Msg {
+            return flashEndMsg{id: id}
+        }))
+    }
+    return tea.Batch(cmds...)
+}
+
+// ringBell rings the terminal's bell. The renderer owns the terminal, so
+// rather than written there BEL goes in front of the view for a moment,
+// long enough for a frame to be drawn with it.
+func (m *model) ringBell() tea.Cmd {
+    m.ringing = true
+    return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+        return bellEndMsg{}
+    })
+}
+
+// transformOutput runs a button's transform function over its output. If the
+// function fails the untouched output is returned along with the error.
+func (m *model) transformOutput(fn *lua.LFunction, output string) string {
+    if m.script == nil {
+        return output
+    }
+    transformed, err := m.script.transform(fn, output)
+    if err != nil {
+        return output + fmt.Sprintf("Error in transform: %v\n", err)
+    }
+    if transformed != "" && !strings.HasSuffix(transformed, "\n") {
+        transformed += "\n"
+    }
+    return transformed
+}
+
+// terminalJob returns the newest job running on a pseudo-terminal in tab,
+// or nil.
+func (m *model) terminalJob(tab int) *job {
+    for i := len(m.jobs) - 1; i >= 0; i-- {
+        if j := m.jobs[i]; j.tab == tab && j.pty != nil {
+            return j
+        }
+    }
+    return nil
+}
+
+// job returns the running job with the given ID, or nil if it has exited.
+func (m model) job(id int) *job {
+    for _, j := range m.jobs {
+        if j.id == id {
+            return j
+        }
+    }
+    return nil
+}
+
+// findButton returns the button called name.
+func (m *model) findButton(name string) (command, bool) {
+    for _, cmd := range m.commands {
+        if cmd.name == name {
+            return cmd, true
+        }
+    }
+    return command{}, false
+}
+
+// runButton runs a configured button with environment variables in its
+// command and working directory expanded and its placeholders filled in,
+// followed by any extra arguments. The extra arguments come from the user
+// and are passed through untouched.
+func (m *model) runButton(cmd command, args ...string) tea.Cmd {
+    if host, ok := m.targets[cmd.target]; ok {
+        cmd.target = host
+    }
+    return m.fillPlaceholders(cmd.expand(), runWith(args))
+}
+
+// runCommand starts cmd in the background and returns the tea.Cmd that
+// streams its output into the current tab.
+func (m *model) runCommand(cmd command) tea.Cmd {
     if len(cmd.cmd) == 0 {
-        return
+        return nil
     }
 
     if cmd.prompt {
         m.input.SetValue("")
         m.input.Focus()
         m.focus = focusInput
-        return
+        return nil
     }
 
-    m.output += fmt.Sprintf("Running command: %s\n", strings.Join(cmd.cmd, " "))
-    c := exec.Command(cmd.cmd[0], cmd.cmd[1:]...)
-    var out bytes.Buffer
-    c.Stdout = &out
-    c.Stderr = &out
+    if cmd.tmuxTarget == "" {
+        cmd.tmuxTarget = m.tmuxTarget
+    }
+    cmd = m.resolveGlob(cmd)
 
-    if err := c.Run(); err != nil {
-        m.output += fmt.Sprintf("Error: %v\n", err)
-    } else {
-        m.output += out.String()
+    line := strings.Join(cmd.cmd, " ")
+    if len(cmd.pipe) > 0 {
+        line += " | " + strings.Join(cmd.pipe, " ")
+    }
+    if cmd.target != "" {
+        line += " on " + cmd.target
+    }
+    if cmd.tmuxTarget != "" {
+        line += " in tmux pane " + cmd.tmuxTarget
     }
-    m.viewports[m.currentTab].SetContent(m.output)
-    m.viewports[m.currentTab].GotoBottom()
+    // Scripts from the editor span lines, their header doesn't
+    line = strings.ReplaceAll(line, "\n", `\n`)
+    if m.recorder != nil {
+        m.recorder.marker(line)
+    }
+    m.appendOutput(m.currentTab, runHeader(line, time.Now()))
+    m.tabs[m.currentTab].elevation = cmd.elevation()
+    m.tabs[m.currentTab].icon = cmd.shownIcon()
+    m.tabs[m.currentTab].run = nil
 
     // Reset input and focus after running a command
     m.input.SetValue("")
     m.focus = focusList
     m.prompInput = false // Reset the prompt input flag
+
+    if cmd.interactive && cmd.tmuxTarget == "" {
+        return m.runInteractive(cmd)
+    }
+
+    m.nextJobID++
+    j := &job{id: m.nextJobID, tab: m.currentTab, cmd: cmd.elevate().remote().tmux(), input: cmd.input || cmd.readsPassword(), maxShown: maxShownOutput}
+    vp := m.tabs[m.currentTab].viewport
+    j.size = pty.Winsize{Rows: uint16(vp.Height), Cols: uint16(vp.Width)}
+    m.jobs = append(m.jobs, j)
+    m.tabs[m.currentTab].run = j
+
+    cmds := []tea.Cmd{j.start(j.cmd.cmd)}
+    if cmd.progress != nil {
+        m.progressJob = j.id
+        cmds = append(cmds, m.setProgress(0))
+    }
+    if len(m.jobs) == 1 && !m.reducedMotion {
+        // First command in flight, start the spinner
+        cmds = append(cmds, m.spinner.Tick)
+    }
+    if m.script != nil {
+        cmds = append(cmds, m.runHook("on_start", m.script.commandValue(cmd)))
+    }
+    return tea.Batch(cmds...)
 }
 
 func (m *model) filterOutput() {
-    lines := strings.Split(m.output, "\n")
+    lines := m.tabs[m.currentTab].lines
     idx, err := fuzzyfinder.Find(
         lines,
         func(i int) string {
@@ -403,76 +1438,188 @@ func (m *model) filterOutput() {
         },
     )
     if err == nil {
-        m.viewports[m.currentTab].SetContent(lines[idx])
+        m.tabs[m.currentTab].viewport.SetContent(lines[idx])
     }
 }
 
+// View draws the model, see ringBell for the BEL in front of it.
 func (m model) View() string {
-    var listStyle, viewportStyle, inputStyle lipgloss.Style
-    switch m.focus {
-    case focusList:
-        listStyle = focusedBorder
-        viewportStyle = normalBorder
-        inputStyle = normalBorder
-    case focusViewport:
-        listStyle = normalBorder
-        viewportStyle = focusedBorder
-        inputStyle = normalBorder
-    case focusInput:
-        listStyle = normalBorder
-        viewportStyle = normalBorder
-        inputStyle = focusedBorder
+    if m.ringing {
+        return "\a" + m.view()
+    }
+    return m.view()
+}
+
+func (m model) view() string {
+    listStyle := paneStyle(focusList, m.focus == focusList)
+    viewportStyle := paneStyle(focusViewport, m.focus == focusViewport)
+    inputStyle := paneStyle(focusInput, m.focus == focusInput)
+    // The output border of a tab flashes after a failure
+    flashStyle := func(style lipgloss.Style) func(tab int) lipgloss.Style {
+        return func(tab int) lipgloss.Style {
+            if m.flashing && m.flashTab == tab {
+                return style.Copy().BorderForeground(failureColor)
+            }
+            return style
+        }
     }
 
     // Render tabs
     var tabViews []string
     for i, t := range m.tabs {
+        title := withIcon(t.icon, t.streamsTitle())
         var style lipgloss.Style
-        if i == m.currentTab {
+        if i == m.currentTab || (m.split != splitOff && (i == m.splitTabs[0] || i == m.splitTabs[1])) {
             style = activeTab
+            if m.accessible {
+                title = "[" + title + "]"
+            }
         } else {
             style = tab
         }
-        tabViews = append(tabViews, style.Render(t))
+        if m.flashing && i == m.flashTab {
+            style = style.Copy().Foreground(failureColor).Bold(true)
+        }
+        view := style.Render(title)
+        if t.elevation != "" {
+            view += elevatedBanner.Render("⚠ " + t.elevation)
+        }
+        tabViews = append(tabViews, view)
     }
 
-    tabs := lipgloss.JoinHorizontal(lipgloss.Top, tabGap.Render("|"), lipgloss.JoinHorizontal(lipgloss.Top, tabViews...))
+    gap := tabGap.Render("|")
+    tabs := lipgloss.JoinHorizontal(lipgloss.Top, gap, lipgloss.JoinHorizontal(lipgloss.Top, tabViews...))
 
-    listView := listStyle.Render(m.list.View())
-    viewportView := viewportStyle.Render(m.viewports[m.currentTab].View())
-    inputView := inputStyle.Render(m.input.View())
+    if m.list.Paginator.TotalPages > manyPages || m.accessible {
+        // A dot per page would be built only to be found too wide, or
+        // read out
+        m.list.Paginator.Type = paginator.Arabic
+    } else {
+        m.list.Paginator.Type = paginator.Dots
+    }
+    listView := listStyle.Width(m.listDimensions.width + listStyle.GetHorizontalPadding()).Render(m.list.View())
+    var viewportView string
+    if m.split != splitOff {
+        viewportView = m.splitView(flashStyle(viewportStyle), flashStyle(paneStyle(focusViewport, false)))
+    } else {
+        viewportView = flashStyle(viewportStyle)(m.currentTab).Render(m.tabs[m.currentTab].viewport.View())
+    }
+    input := m.input
+    if j := m.inputJob(m.currentTab); j != nil && !m.prompInput && m.pipeSource == nil && !m.searching && !m.exporting && !m.confirming && m.passwordJob == 0 {
+        input.Placeholder = fmt.Sprintf("Send input to %s...", j.cmd.name)
+        if m.stdinMode {
+            input.Prompt = "stdin" + input.Prompt
+            input.Placeholder = fmt.Sprintf("Send input to %s, ctrl+d to end it...", j.cmd.name)
+        }
+    }
+    if m.viNormal {
+        // Mark vi's normal mode, in the room the text would take
+        input.Prompt = "N" + input.Prompt
+        input.Width--
+    }
+    // Line the input up with the output, however wide their borders
+    input.Width += viewportStyle.GetHorizontalFrameSize() - inputStyle.GetHorizontalFrameSize()
+    if !m.layoutOf(m.layoutTab()).showsList() {
+        // and with the widened output
+        input.Width += m.listDimensions.width + listStyle.GetHorizontalFrameSize()
+    }
+    inputView := inputStyle.Height(m.tiDimensions.height + inputStyle.GetVerticalPadding()).Render(input.View())
+    if m.promptError != "" {
+        inputView = lipgloss.JoinVertical(lipgloss.Left, inputView, errorStyle.Render(" "+m.promptError))
+    }
 
-    helpView := ""
-    if m.showHelp {
-        helpView = "\n\n" + m.help.View(m.keys)
+    statusView := ""
+    if len(m.jobs) > 0 {
+        names := make([]string, len(m.jobs))
+        for i, j := range m.jobs {
+            names[i] = j.cmd.name
+            if elevation := j.cmd.elevation(); elevation != "" {
+                names[i] += " " + elevation
+            }
+        }
+        statusView = "\n" + m.spinner.View() + statusStyle.Render("Running: "+strings.Join(names, ", "))
+        if m.progressJob != 0 {
+            statusView += "  " + m.progressView()
+        }
+    }
+    if m.replay != nil {
+        if statusView == "" {
+            statusView = "\n"
+        } else {
+            statusView += "  "
+        }
+        statusView += statusStyle.Render(m.replayStatus())
+    }
+    if search := m.searchStatus(); search != "" {
+        if statusView == "" {
+            statusView = "\n"
+        } else {
+            statusView += "  "
+        }
+        statusView += statusStyle.Render(search)
+    }
+    if m.marking != "" {
+        statusView = "\n" + m.markQuestion()
+    }
+    if m.confirmingQuit {
+        statusView = "\n" + m.quitQuestion()
     }
 
+    if m.cheatSheet {
+        return m.cheatSheetView()
+    }
+    if m.editing {
+        return m.editorView()
+    }
+    helpView := "\n\n" + m.help.ShortHelpView(m.shortHelp())
+
+    top := docStyle.GetMarginTop()
+    if m.zen {
+        m.regions.placeTabs(docStyle.GetMarginLeft(), top, gap, nil)
+    } else {
+        m.regions.placeTabs(docStyle.GetMarginLeft(), top, gap, tabViews)
+        top += lipgloss.Height(tabs)
+    }
+    m.regions.place(&m, docStyle.GetMarginLeft(), top, listView, viewportView, inputView)
+
+    if m.zen {
+        return docStyle.Render(m.layoutView(listView, viewportView, inputView)) + statusView
+    }
     return docStyle.Render(
         lipgloss.JoinVertical(
             lipgloss.Left,
             tabs,
-            lipgloss.JoinHorizontal(
-                lipgloss.Top,
-                listView,
-                lipgloss.JoinVertical(
-                    lipgloss.Left,
-                    viewportView,
-                    inputView,
-                ),
-            ),
+            m.layoutView(listView, viewportView, inputView),
         ),
-    ) + helpView
+    ) + statusView + helpView
 }
 
 type listItem struct {
-    title string
+    title       string
+    group       string
+    description string
+    elevation   string // Marked after the title so sudo and remote buttons stand out
+    icon        string // Shown before the title, see command.shownIcon
+    tags        []string
+    index       int // Of the button in model.commands
+    pinned      bool
+    lastPinned  bool   // Underlined to set the pinned buttons apart from the rest
+    filterValue string // The title and tags, joined once rather than for every filter keystroke
+}
+
+func (i listItem) Title() string {
+    if i.group != "" {
+        return i.group + ": " + i.title
+    }
+    return i.title
 }
 
-func (i listItem) Title() string       { return i.title }
 func (i listItem) Description() string { return "" }
-func (i listItem) FilterValue() string { return i.title }
+func (i listItem) FilterValue() string { return i.filterValue }
 
-type customDelegate struct{}
+type customDelegate struct {
+    cursor bool // Mark the selected button with "> ", for when there are no colors
+}
 
 func (d customDelegate) Height() int                               { return 1 }
 func (d customDelegate) Spacing() int                              { return 0 }
@@ -483,26 +1630,160 @@ func (d customDelegate) Render(w io.Writer, m list.Model, index int, item list.I
         return
     }
 
-    button := inactiveButton.Render(i.Title())
+    title := withIcon(i.icon, i.Title())
+    if i.pinned {
+        title = pinMarker + title
+    }
+    if d.cursor && m.Index() == index {
+        title = "> " + title
+    }
+    style := inactiveButton
     if m.Index() == index {
-        button = activeButton.Render(i.Title())
+        style = activeButton
+    } else if i.elevation != "" {
+        style = elevatedButton
+    }
+    button := style.Render(title)
+    if i.elevation != "" {
+        button += elevatedStyle.Render("⚠ " + i.elevation)
+    }
+    if m.Index() == index {
+        if room := m.Width() - lipgloss.Width(button) - 1; i.description != "" && room > 1 {
+            description := i.description
+            if len([]rune(description)) > room {
+                description = string([]rune(description)[:room-1]) + "…"
+            }
+            button += " " + statusStyle.Render(description)
+        }
     }
 
+    if i.lastPinned && !m.SettingFilter() && m.FilterState() != list.FilterApplied {
+        if room := m.Width() - lipgloss.Width(button); room > 0 {
+            button += pinnedDivider.Render(strings.Repeat(" ", room))
+        }
+    }
     fmt.Fprintf(w, "%s", button)
 }
 
 func main() {
-    commands, vpDimensions, listDimensions, tiDimensions, completions, err := loadConfig()
+    configPath := flag.String("config", "", "path to the config file")
+    listen := flag.String("listen", "", "unix socket to accept requests from other programs on")
+    httpAddr := flag.String("http", "", "address to serve the status and trigger endpoints on, e.g. :8765")
+    record := flag.String("record", "", "file to record the commands run and their output to, in asciinema's format")
+    inline := flag.Bool("inline", false, "draw in the terminal's normal screen, so the last screen stays in its scrollback after exit")
+    themeName := flag.String("theme", "", "theme to draw with, default or high-contrast, instead of the config's")
+    accessible := flag.Bool("accessible", false, "draw plainly for screen readers, without borders or colors, printing focus changes and results as lines of text")
+    flag.Parse()
+
+    if flag.Arg(0) == "init" {
+        if err := runInit(flag.Args()[1:]); err != nil {
+            log.Fatalf("Error: %v", err)
+        }
+        return
+    }
+
+    if *themeName != "" {
+        if _, ok := themes[*themeName]; !ok {
+            log.Fatalf("Error: unknown theme %q, expected default or high-contrast", *themeName)
+        }
+        themeFlag = *themeName
+    }
+
+    path, err := findConfig(*configPath)
     if err != nil {
         log.Fatalf("Error loading config: %v", err)
     }
+    cfg, err := loadConfig(path)
+    if err != nil {
+        log.Fatalf("Error loading config: %v", err)
+    }
+
+    var headless func(config, []string) (int, error)
+    switch flag.Arg(0) {
+    case "run":
+        headless = runHeadless
+    case "batch":
+        headless = runBatch
+    }
+    if headless != nil {
+        code, err := headless(cfg, flag.Args()[1:])
+        if err != nil {
+            log.Fatalf("Error: %v", err)
+        }
+        os.Exit(code)
+    }
+
+    if *accessible {
+        // Before anything is drawn, some parts of the list are only once
+        lipgloss.SetColorProfile(termenv.Ascii)
+    }
+    m := initialModel(cfg)
+    if *accessible {
+        m.makeAccessible()
+    }
+    if flag.Arg(0) == "replay" {
+        if flag.NArg() != 2 {
+            log.Fatalf("Error: usage: cmdtui replay <session.cast>")
+        }
+        r, err := loadReplay(flag.Arg(1))
+        if err != nil {
+            log.Fatalf("Error: %v", err)
+        }
+        m.startReplay(r)
+    }
+    if m.watcher, err = watchConfig(cfg); err != nil {
+        log.Printf("Not watching config for changes: %v", err)
+    }
+    if m.paths, err = newPathWatcher(); err != nil {
+        log.Printf("Not watching watch_paths for changes: %v", err)
+    }
+    m.watchButtonPaths()
+    if *listen != "" || *httpAddr != "" {
+        m.control = newControlServer()
+        defer m.control.close()
+    }
+    if *listen != "" {
+        if err := m.control.listenUnix(*listen); err != nil {
+            log.Fatalf("Error: %v", err)
+        }
+    }
+    if *httpAddr != "" {
+        if err := m.control.listenHTTP(*httpAddr, cfg.httpToken); err != nil {
+            log.Fatalf("Error: %v", err)
+        }
+    }
+    if *record != "" {
+        if m.recorder, err = newRecorder(*record); err != nil {
+            log.Fatalf("Error recording: %v", err)
+        }
+        defer func() {
+            if err := m.recorder.close(); err != nil {
+                log.Printf("Error recording: %v", err)
+            }
+        }()
+    }
 
-    p := tea.NewProgram(
-        initialModel(commands, vpDimensions, listDimensions, tiDimensions, completions),
-        tea.WithAltScreen(),      // Use alternate screen buffer
+    // Ask the terminal for its background before the TUI starts reading
+    // the input, where the answer would turn up, rather than when the first
+    // color is drawn
+    lipgloss.HasDarkBackground()
+
+    options := []tea.ProgramOption{
         tea.WithMouseCellMotion(), // Enable mouse support
-    )
-    if err := p.Start(); err != nil {
+    }
+    if !*inline && !*accessible {
+        // Lines printed for screen readers need the normal screen
+        options = append(options, tea.WithAltScreen()) // Use alternate screen buffer
+    }
+    p := tea.NewProgram(m, options...)
+    final, err := p.Run()
+    if final, ok := final.(model); ok {
+        // Quitting without being asked, on SIGTERM for example, leaves
+        // commands running
+        final.killJobs()
+        final.dropSpills()
+    }
+    if err != nil {
         log.Fatalf("Error: %v", err)
     }
 }
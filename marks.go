@@ -0,0 +1,54 @@
+package main
+
+import (
+    "sort"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// startMark waits for the letter naming a mark, to set it at the top of
+// the current tab's output or to jump back to it. Marks are kept per tab
+// until cmdtui exits.
+func (m *model) startMark(mode string) {
+    m.marking = mode
+}
+
+// finishMark handles the key typed after the mark key: a letter sets or
+// jumps to that mark, esc or anything else gives up.
+func (m *model) finishMark(msg tea.KeyMsg) {
+    mode := m.marking
+    m.marking = ""
+    if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+        return
+    }
+    name := string(msg.Runes)
+    t := &m.tabs[m.currentTab]
+    if mode == "set" {
+        if t.marks == nil {
+            t.marks = make(map[string]int)
+        }
+        t.marks[name] = t.viewport.YOffset
+        return
+    }
+    if line, ok := t.marks[name]; ok {
+        t.jumpTo(line)
+    }
+}
+
+// markQuestion is shown in the status line while a mark key waits for its
+// letter.
+func (m *model) markQuestion() string {
+    if m.marking == "set" {
+        return statusStyle.Render("Set mark: type a letter")
+    }
+    var names []string
+    for name := range m.tabs[m.currentTab].marks {
+        names = append(names, name)
+    }
+    if len(names) == 0 {
+        return statusStyle.Render("No marks in this tab yet")
+    }
+    sort.Strings(names)
+    return statusStyle.Render("Jump to mark: " + strings.Join(names, " "))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+    "github.com/charmbracelet/bubbles/cursor"
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// setReducedMotion switches reduced motion, set with reduced_motion or by
+// --accessible: the spinner stands still, the progress bar jumps rather
+// than slides, the cursor doesn't blink and failures don't flash. It
+// spares those who find motion distracting, and slow links the redraws.
+func (m *model) setReducedMotion(reduced bool) {
+    m.reducedMotion = reduced
+    mode := cursor.CursorBlink
+    if reduced {
+        mode = cursor.CursorStatic
+    }
+    m.input.Cursor.SetMode(mode)
+    m.editor.Cursor.SetMode(mode)
+}
+
+// setProgress moves the progress bar to percent.
+func (m *model) setProgress(percent float64) tea.Cmd {
+    m.progressPercent = percent
+    if m.reducedMotion {
+        return nil
+    }
+    return m.progress.SetPercent(percent)
+}
+
+// progressView draws the progress bar, already where it is going when
+// motion is reduced.
+func (m *model) progressView() string {
+    if m.reducedMotion {
+        return m.progress.ViewAs(m.progressPercent)
+    }
+    return m.progress.View()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickTime is how soon a second click on the same button has to
+// follow the first to run it.
+const doubleClickTime = 400 * time.Millisecond
+
+// region is a rectangle of the screen, in cells from the top left corner.
+type region struct {
+    x, y          int
+    width, height int
+}
+
+// contains reports whether the cell at x, y is inside r.
+func (r region) contains(x, y int) bool {
+    return x >= r.x && x < r.x+r.width && y >= r.y && y < r.y+r.height
+}
+
+// buttonClick is a click on a button in the list.
+type buttonClick struct {
+    item int // Index into the list's visible items
+    at   time.Time
+}
+
+// screenRegions records where the last View drew each pane, so mouse clicks
+// can be matched to what is under them. View can't change the model, so the
+// model keeps a pointer to it.
+type screenRegions struct {
+    list  region    // Zero when the layout hides the list
+    panes [2]region // The output, or the panes of a split in the order of splitTabs
+    input region    // Zero when the layout hides the input
+    tabs  []region  // Titles in the tab bar, empty in zen mode
+}
+
+// place records the regions of the panes arranged by layoutView, drawn with
+// their top left corner at x, y.
+func (r *screenRegions) place(m *model, x, y int, listView, viewportView, inputView string) {
+    r.list, r.panes, r.input = region{}, [2]region{}, region{}
+    outputX := x
+    switch m.layoutOf(m.layoutTab()) {
+    case layoutLeft:
+        r.list = region{x, y, lipgloss.Width(listView), lipgloss.Height(listView)}
+        outputX += r.list.width
+    case layoutRight:
+        r.list = region{x + lipgloss.Width(viewportView), y, lipgloss.Width(listView), lipgloss.Height(listView)}
+    }
+
+    output := region{outputX, y, lipgloss.Width(viewportView), lipgloss.Height(viewportView)}
+    r.panes[0] = output
+    if m.split != splitOff {
+        // Each pane is its viewport plus a border and padding
+        frameWidth, frameHeight := paneStyle(focusViewport, false).GetFrameSize()
+        first := m.tabs[m.splitTabs[0]].viewport
+        r.panes[1] = output
+        if m.split == splitSideBySide {
+            r.panes[0].width = first.Width + frameWidth
+            r.panes[1].x += r.panes[0].width
+            r.panes[1].width -= r.panes[0].width
+        } else {
+            r.panes[0].height = first.Height + frameHeight
+            r.panes[1].y += r.panes[0].height
+            r.panes[1].height -= r.panes[0].height
+        }
+    }
+
+    if m.visible(focusInput) {
+        r.input = region{outputX, y + output.height, lipgloss.Width(inputView), lipgloss.Height(inputView)}
+    }
+}
+
+// placeTabs records the regions of the tab titles, drawn one after the
+// other following gap from x, y. No tabs are recorded for a nil tabViews.
+func (r *screenRegions) placeTabs(x, y int, gap string, tabViews []string) {
+    r.tabs = r.tabs[:0]
+    if tabViews == nil {
+        return
+    }
+    x += lipgloss.Width(gap)
+    for _, view := range tabViews {
+        r.tabs = append(r.tabs, region{x, y, lipgloss.Width(view), lipgloss.Height(view)})
+        x += lipgloss.Width(view)
+    }
+}
+
+// click switches to the tab whose title is at x, y, or moves focus to the
+// pane there, in a split to the output pane there. Clicking a button selects
+// it, and clicking it again quickly runs it.
+func (m *model) click(x, y int) tea.Cmd {
+    r := m.regions
+    for i, t := range r.tabs {
+        if t.contains(x, y) && i < len(m.tabs) {
+            m.selectTab(i)
+            return nil
+        }
+    }
+    switch {
+    case r.list.contains(x, y):
+        m.focus = focusList
+        item, ok := m.buttonAt(x, y)
+        if !ok {
+            return nil
+        }
+        now := time.Now()
+        double := m.lastClick.item == item && now.Sub(m.lastClick.at) < doubleClickTime
+        m.list.Select(item)
+        if double {
+            m.lastClick = buttonClick{}
+            return m.runSelected()
+        }
+        m.lastClick = buttonClick{item: item, at: now}
+    case r.input.contains(x, y):
+        m.focus = focusInput
+        m.input.Focus()
+    case r.panes[0].contains(x, y):
+        m.focusPane(0)
+    case m.split != splitOff && r.panes[1].contains(x, y):
+        m.focusPane(1)
+    }
+    return nil
+}
+
+// wheel scrolls the output pane under the mouse, whichever pane has focus,
+// and reports whether there was one.
+func (m *model) wheel(msg tea.MouseMsg) (tea.Cmd, bool) {
+    for i, pane := range m.regions.panes {
+        if !pane.contains(msg.X, msg.Y) || (i == 1 && m.split == splitOff) {
+            continue
+        }
+        tab := m.currentTab
+        if m.split != splitOff {
+            tab = m.splitTabs[i]
+        }
+        var cmd tea.Cmd
+        m.tabs[tab].viewport, cmd = m.tabs[tab].viewport.Update(msg)
+        if m.isCompared(tab) {
+            // Compared panes scroll together
+            m.tabs[m.splitTabs[1-i]].viewport.SetYOffset(m.tabs[tab].viewport.YOffset)
+        }
+        return cmd, true
+    }
+    return nil, false
+}
+
+// middleClick runs the button at x, y.
+func (m *model) middleClick(x, y int) tea.Cmd {
+    item, ok := m.buttonAt(x, y)
+    if !ok {
+        return nil
+    }
+    m.focus = focusList
+    m.list.Select(item)
+    return m.runSelected()
+}
+
+// buttonAt returns the index among the list's visible items of the button
+// drawn at x, y, and false when there is none.
+func (m *model) buttonAt(x, y int) (int, bool) {
+    r := m.regions.list
+    if !r.contains(x, y) || m.list.SettingFilter() {
+        return 0, false
+    }
+    // The buttons follow the list's border, padding and title
+    top := r.y + paneStyle(focusList, false).GetBorderTopSize() + paneStyle(focusList, false).GetPaddingTop()
+    if m.list.ShowTitle() || m.list.ShowFilter() {
+        top += lipgloss.Height(m.list.Styles.TitleBar.Render(m.list.Title))
+    }
+    if y < top {
+        return 0, false
+    }
+    d := customDelegate{}
+    row := (y - top) / (d.Height() + d.Spacing())
+    if row >= m.list.Paginator.ItemsOnPage(len(m.list.VisibleItems())) {
+        return 0, false
+    }
+    return m.list.Paginator.Page*m.list.Paginator.PerPage + row, true
+}
+
+// focusPane moves focus to the output, to pane i of splitTabs in a split.
+func (m *model) focusPane(i int) {
+    m.focus = focusViewport
+    if m.split != splitOff && m.splitPane != i {
+        m.switchPane()
+    }
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+    "fmt"
+    "os/exec"
+    "runtime"
+    "strings"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// notifyFinished returns a tea.Cmd that announces a finished command with a
+// desktop notification. Failures are ignored, a missing notifier should not
+// interrupt the session.
+func notifyFinished(name string, err error, elapsed time.Duration) tea.Cmd {
+    return func() tea.Msg {
+        body := fmt.Sprintf("Finished in %s", elapsed.Round(time.Second))
+        if err != nil {
+            body = fmt.Sprintf("Failed after %s: %v", elapsed.Round(time.Second), err)
+        }
+        _ = sendNotification("cmdtui: "+name, body)
+        return nil
+    }
+}
+
+// sendNotification shows a desktop notification using whatever the platform
+// provides: osascript on macOS, a PowerShell toast on Windows and
+// notify-send everywhere else.
+func sendNotification(title, body string) error {
+    var c *exec.Cmd
+    switch runtime.GOOS {
+    case "darwin":
+        script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(body), appleScriptQuote(title))
+        c = exec.Command("osascript", "-e", script)
+    case "windows":
+        script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text[0].AppendChild($xml.CreateTextNode(%s)) > $null
+$text[1].AppendChild($xml.CreateTextNode(%s)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('cmdtui').Show([Windows.UI.Notifications.ToastNotification]::new($xml))`,
+            powerShellQuote(title), powerShellQuote(body))
+        c = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+    default:
+        c = exec.Command("notify-send", "--app-name=cmdtui", title, body)
+    }
+    return c.Run()
+}
+
+func appleScriptQuote(s string) string {
+    s = strings.ReplaceAll(s, `\`, `\\`)
+    return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func powerShellQuote(s string) string {
+    return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+)
+
+// numberSpec bounds the argument of a prompt with type number. The prompt
+// starts at the default and + and - step it, staying within min and max.
+// With a whole step only whole numbers are allowed.
+type numberSpec struct {
+    min     float64
+    max     float64
+    step    float64
+    initial float64
+}
+
+// extractNumber reads the bounds of a number prompt.
+func extractNumber(node configNode) numberSpec {
+    spec := numberSpec{
+        min:  node.key("min").number(math.Inf(-1)),
+        max:  node.key("max").number(math.Inf(1)),
+        step: node.key("step").number(1),
+    }
+    if spec.min > spec.max {
+        node.key("max").fail("must not be less than min")
+    }
+    if spec.step <= 0 {
+        node.key("step").fail("must be greater than zero")
+        spec.step = 1
+    }
+    spec.initial = node.key("default").number(spec.clamp(0))
+    if problem := spec.check(spec.format(spec.initial)); problem != "" {
+        node.key("default").fail("%s", problem)
+    }
+    return spec
+}
+
+// clamp returns the closest value to v within the bounds.
+func (spec numberSpec) clamp(v float64) float64 {
+    return math.Min(math.Max(v, spec.min), spec.max)
+}
+
+// whole reports whether only whole numbers are allowed.
+func (spec numberSpec) whole() bool {
+    return spec.step == math.Trunc(spec.step)
+}
+
+// format writes v the way it is passed to the command.
+func (spec numberSpec) format(v float64) string {
+    return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// check returns why value is not a number within the bounds, or "" when
+// it is.
+func (spec numberSpec) check(value string) string {
+    v, err := strconv.ParseFloat(value, 64)
+    if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+        return "must be a number"
+    }
+    if spec.whole() && v != math.Trunc(v) {
+        return "must be a whole number"
+    }
+    if v < spec.min {
+        return "must be at least " + spec.format(spec.min)
+    }
+    if v > spec.max {
+        return "must be at most " + spec.format(spec.max)
+    }
+    return ""
+}
+
+// stepped returns value moved by dir steps and kept within the bounds. A
+// value that is not a number starts over from the default.
+func (spec numberSpec) stepped(value string, dir int) string {
+    v, err := strconv.ParseFloat(value, 64)
+    if err != nil {
+        return spec.format(spec.initial)
+    }
+    v = spec.clamp(v + float64(dir)*spec.step)
+    // Keep steps like 0.1 from piling up rounding errors
+    return spec.format(math.Round(v*1e9) / 1e9)
+}
+
+// describe returns the bounds of spec for the help line, "" when it has
+// none.
+func (spec numberSpec) describe() string {
+    switch {
+    case !math.IsInf(spec.min, 0) && !math.IsInf(spec.max, 0):
+        return fmt.Sprintf("%s to %s", spec.format(spec.min), spec.format(spec.max))
+    case !math.IsInf(spec.min, 0):
+        return "at least " + spec.format(spec.min)
+    case !math.IsInf(spec.max, 0):
+        return "at most " + spec.format(spec.max)
+    }
+    return ""
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/charmbracelet/bubbles/key"
+    "github.com/charmbracelet/bubbles/viewport"
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/charmbracelet/lipgloss"
+    "github.com/charmbracelet/x/ansi"
+)
+
+// outputView scrolls through a tab's output like the viewport.Model it
+// stands in for, with the same keys. It keeps its content as lines that
+// more output is added to without copying what is already there, and only
+// draws the lines in view, so long sessions stay quick.
+type outputView struct {
+    Width           int
+    Height          int
+    YOffset         int
+    KeyMap          viewport.KeyMap
+    MouseWheelDelta int // Lines scrolled per turn of the wheel
+    lines           []string
+    output          bool // The lines are the tab's output, see showAdded
+}
+
+func newOutputView(width, height int) outputView {
+    return outputView{Width: width, Height: height, KeyMap: viewport.DefaultKeyMap(), MouseWheelDelta: 3}
+}
+
+// SetContent replaces the lines shown with those of s, which is something
+// other than the tab's output, such as a hint.
+func (v *outputView) SetContent(s string) {
+    v.setLines(strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n"))
+    v.output = false
+}
+
+// setLines replaces the lines shown with the tab's output. The view keeps
+// lines and adds to it.
+func (v *outputView) setLines(lines []string) {
+    v.lines, v.output = lines, true
+    if v.YOffset > len(v.lines)-1 {
+        v.GotoBottom()
+    }
+}
+
+func (v *outputView) TotalLineCount() int {
+    return len(v.lines)
+}
+
+func (v *outputView) maxYOffset() int {
+    return max(0, len(v.lines)-v.Height)
+}
+
+func (v *outputView) AtTop() bool {
+    return v.YOffset <= 0
+}
+
+func (v *outputView) AtBottom() bool {
+    return v.YOffset >= v.maxYOffset()
+}
+
+// SetYOffset scrolls so line n is at the top, as far as there are lines.
+func (v *outputView) SetYOffset(n int) {
+    v.YOffset = min(max(n, 0), v.maxYOffset())
+}
+
+func (v *outputView) GotoBottom() {
+    v.SetYOffset(v.maxYOffset())
+}
+
+// Update scrolls the view for the keys of its KeyMap and the mouse wheel.
+func (v outputView) Update(msg tea.Msg) (outputView, tea.Cmd) {
+    switch msg := msg.(type) {
+    case tea.KeyMsg:
+        switch {
+        case key.Matches(msg, v.KeyMap.PageDown):
+            v.SetYOffset(v.YOffset + v.Height)
+        case key.Matches(msg, v.KeyMap.PageUp):
+            v.SetYOffset(v.YOffset - v.Height)
+        case key.Matches(msg, v.KeyMap.HalfPageDown):
+            v.SetYOffset(v.YOffset + v.Height/2)
+        case key.Matches(msg, v.KeyMap.HalfPageUp):
+            v.SetYOffset(v.YOffset - v.Height/2)
+        case key.Matches(msg, v.KeyMap.Down):
+            v.SetYOffset(v.YOffset + 1)
+        case key.Matches(msg, v.KeyMap.Up):
+            v.SetYOffset(v.YOffset - 1)
+        }
+    case tea.MouseMsg:
+        if msg.Action != tea.MouseActionPress {
+            break
+        }
+        switch msg.Button {
+        case tea.MouseButtonWheelUp:
+            v.SetYOffset(v.YOffset - v.MouseWheelDelta)
+        case tea.MouseButtonWheelDown:
+            v.SetYOffset(v.YOffset + v.MouseWheelDelta)
+        }
+    }
+    return v, nil
+}
+
+// View draws the lines in view, padded to the size of the view.
+func (v outputView) View() string {
+    top := min(max(v.YOffset, 0), len(v.lines))
+    bottom := min(max(top, v.YOffset+v.Height), len(v.lines))
+    return lipgloss.NewStyle().
+        Width(v.Width).
+        Height(v.Height).
+        MaxHeight(v.Height).
+        MaxWidth(v.Width).
+        Render(strings.Join(v.lines[top:bottom], "\n"))
+}
+
+// text returns the tab's output.
+func (t *outputTab) text() string {
+    return strings.Join(t.lines, "\n")
+}
+
+// addText adds text to the tab's output, continuing its unfinished last
+// line.
+func (t *outputTab) addText(text string) {
+    parts := strings.Split(text, "\n")
+    if len(t.lines) == 0 {
+        t.lines = []string{""}
+    }
+    t.lines[len(t.lines)-1] += parts[0]
+    t.lines = append(t.lines, parts[1:]...)
+    t.size += len(text)
+}
+
+// setText replaces the tab's output.
+func (t *outputTab) setText(text string) {
+    t.lines, t.size = nil, 0
+    if text != "" {
+        t.addText(text)
+    }
+}
+
+// showOutput puts the tab's output in its view, fitting long lines to its
+// width: wrapped when wrap is on, cut off otherwise. Left as they are they
+// would stretch the pane.
+func (t *outputTab) showOutput() {
+    var shown []string
+    lines, runs := t.lines, true
+    if stream, ok := t.streamOutput(); ok {
+        lines, runs = strings.Split(stream, "\n"), false
+    } else if t.spill != nil {
+        shown = []string{t.spillNotice()}
+    }
+    t.matches, t.matchLines, t.runs = 0, nil, nil
+    t.viewport.setLines(t.renderLines(shown, lines, runs))
+}
+
+// showAdded shows the lines of output from the line that was the last one,
+// which may have been continued, redrawing nothing before it. The whole
+// output is redrawn when its end is folded or only one stream is shown.
+func (t *outputTab) showAdded(from int) {
+    last := len(t.runs) - 1
+    if from < 0 || !t.viewport.output || (last >= 0 && t.folded[t.runs[last].header]) {
+        t.showOutput()
+        return
+    }
+    if _, ok := t.streamOutput(); ok {
+        t.showOutput()
+        return
+    }
+    // Forget what the old last line added
+    t.matches = t.tailHits
+    for len(t.matchLines) > 0 && t.matchLines[len(t.matchLines)-1] >= t.tail {
+        t.matchLines = t.matchLines[:len(t.matchLines)-1]
+    }
+    for len(t.runs) > 0 && t.runs[len(t.runs)-1].line >= t.tail {
+        t.runs = t.runs[:len(t.runs)-1]
+    }
+    t.viewport.setLines(t.renderLines(t.viewport.lines[:t.tail], t.lines[from:], true))
+}
+
+// renderLines fits lines of output to the view and adds them to shown,
+// noting where runs start, when runs is set, and the matches of the search.
+// Runs that are folded leave only their header, with how much was left out.
+func (t *outputTab) renderLines(shown, lines []string, runs bool) []string {
+    folded, hidden := "", 0 // Header of the run being left out, and its lines so far
+    endFold := func() {
+        if folded != "" && hidden > 0 {
+            folded += statusStyle.Render(fmt.Sprintf("  … %d lines folded", hidden))
+        }
+        if folded != "" {
+            shown = t.fitLine(shown, folded)
+        }
+        folded, hidden = "", 0
+    }
+    for i, line := range lines {
+        if i == len(lines)-1 {
+            // Where the unfinished last line starts, for showAdded
+            endFold()
+            t.tail, t.tailHits = len(shown), t.matches
+        }
+        if runs && isRunHeader(line) {
+            endFold()
+            header := ansiEscape.ReplaceAllString(line, "")
+            t.runs = append(t.runs, runStart{line: len(shown), header: header})
+            if header == t.atRun {
+                line = runCurrent.Render(header)
+            }
+            if t.folded[header] {
+                folded = line
+                continue
+            }
+        } else if folded != "" {
+            hidden++
+            continue
+        }
+        shown = t.fitLine(shown, line)
+    }
+    endFold()
+    return shown
+}
+
+// fitLine adds a line of output to shown, highlighting the search's matches
+// and wrapped or cut off to the view's width.
+func (t *outputTab) fitLine(shown []string, line string) []string {
+    line, matches := highlightMatches(line, t.pattern)
+    t.matches += matches
+    fitted := []string{line}
+    if width := t.viewport.Width; width > 0 && ansi.StringWidth(line) > width {
+        if t.wrap {
+            fitted = strings.Split(ansi.Wrap(line, width, ""), "\n")
+        } else {
+            fitted[0] = ansi.Truncate(line, width, "…")
+            if matches > 0 {
+                // The end of a match may have been cut off
+                fitted[0] += matchEnd
+            }
+        }
+    }
+    for _, l := range fitted {
+        if matches > 0 && strings.Contains(l, matchStart) {
+            t.matchLines = append(t.matchLines, len(shown))
+        }
+        shown = append(shown, l)
+    }
+    return shown
+}
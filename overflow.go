@@ -0,0 +1,112 @@
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "sync"
+)
+
+const (
+    // outputChunk is how much output is read at a time.
+    outputChunk = 64 * 1024
+    // maxLineLength is where overlong lines are broken.
+    maxLineLength = 1024 * 1024
+    // maxShownOutput is how much output of a run the TUI keeps, a lot more
+    // would only slow it down. The rest is saved to a file.
+    maxShownOutput = 8 * 1024 * 1024
+)
+
+// overflow counts the output of a job sent to be shown and, past the job's
+// limit, holds the file the rest is saved to. Both streams share it.
+type overflow struct {
+    mu    sync.Mutex
+    shown int
+    file  *os.File // nil until the limit is reached
+    err   error    // Creating or writing the file failed, the rest is dropped
+}
+
+// readOutput reads the output of j from r a chunk at a time and queues the
+// complete lines of each chunk together.
+func (j *job) readOutput(r io.Reader, stderr bool) {
+    buf := make([]byte, outputChunk)
+    var partial []byte // The start of a line continued in the next chunk
+    for {
+        n, err := r.Read(buf)
+        partial = append(partial, buf[:n]...)
+        end := bytes.LastIndexByte(partial, '\n') + 1
+        if end == 0 && len(partial) >= maxLineLength {
+            end = len(partial)
+        }
+        if err != nil {
+            // The last line may not end in a newline
+            end = len(partial)
+        }
+        if end > 0 {
+            text := strings.TrimSuffix(string(partial[:end]), "\n")
+            lines := strings.Split(text, "\n")
+            for i, line := range lines {
+                // Terminals end lines with \r\n
+                lines[i] = strings.TrimSuffix(line, "\r")
+            }
+            j.send(lines, stderr)
+            partial = append(partial[:0], partial[end:]...)
+        }
+        if err != nil {
+            return
+        }
+    }
+}
+
+// send passes lines of output on to be shown, as far as the job's limit
+// allows. The lines past it go to a file, with a message saying where
+// taking their place.
+func (j *job) send(lines []string, stderr bool) {
+    o := &j.overflow
+    o.mu.Lock()
+    defer o.mu.Unlock()
+
+    shown := len(lines)
+    if j.maxShown > 0 {
+        for i, line := range lines {
+            if o.file != nil || o.err != nil || o.shown+len(line)+1 > j.maxShown {
+                shown = i
+                break
+            }
+            o.shown += len(line) + 1
+        }
+    }
+    if shown > 0 {
+        j.queue(commandOutputMsg{job: j.id, lines: lines[:shown:shown], stderr: stderr})
+    }
+    if shown == len(lines) {
+        return
+    }
+
+    if o.file == nil && o.err == nil {
+        var notice string
+        o.file, o.err = os.CreateTemp("", "cmdtui-output-*.log")
+        if o.err != nil {
+            notice = fmt.Sprintf("Output truncated after %d MB, the rest could not be saved: %v", j.maxShown>>20, o.err)
+        } else {
+            notice = fmt.Sprintf("Output truncated after %d MB, the rest is saved to %s", j.maxShown>>20, o.file.Name())
+        }
+        j.queue(commandOutputMsg{job: j.id, notice: notice})
+    }
+    if o.err == nil {
+        _, o.err = io.WriteString(o.file, strings.Join(lines[shown:], "\n")+"\n")
+    }
+}
+
+// closeOverflow closes the file the output past the job's limit went to,
+// if it got that far.
+func (j *job) closeOverflow() {
+    o := &j.overflow
+    o.mu.Lock()
+    defer o.mu.Unlock()
+    if o.file != nil {
+        o.file.Close()
+    }
+}
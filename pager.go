@@ -0,0 +1,48 @@
+package main
+
+import (
+    "os"
+    "os/exec"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// pagerFinishedMsg is sent when the pager exits and the TUI is back.
+type pagerFinishedMsg struct {
+    err error
+}
+
+// pagerCommand returns the argv of the user's pager, $PAGER or less.
+func pagerCommand() []string {
+    if argv := strings.Fields(os.Getenv("PAGER")); len(argv) > 0 {
+        return argv
+    }
+    return []string{"less", "-R"}
+}
+
+// openPager suspends the TUI and shows the current tab's output in the
+// pager, with any moved to disk. The output goes through a temporary file
+// so the pager can seek.
+func (m *model) openPager() tea.Cmd {
+    output, err := m.tabs[m.currentTab].allOutput()
+    if err != nil {
+        return func() tea.Msg { return pagerFinishedMsg{err: err} }
+    }
+    f, err := os.CreateTemp("", "cmdtui-*.txt")
+    if err != nil {
+        return func() tea.Msg { return pagerFinishedMsg{err: err} }
+    }
+    _, err = f.WriteString(output)
+    f.Close()
+    if err != nil {
+        os.Remove(f.Name())
+        return func() tea.Msg { return pagerFinishedMsg{err: err} }
+    }
+
+    argv := append(pagerCommand(), f.Name())
+    return tea.ExecProcess(exec.Command(argv[0], argv[1:]...), func(err error) tea.Msg {
+        os.Remove(f.Name())
+        return pagerFinishedMsg{err: err}
+    })
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "os/exec"
+    "regexp"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+    fuzzyfinder "github.com/ktr0731/go-fuzzyfinder"
+)
+
+// placeholderRef matches {name} in a button's command.
+var placeholderRef = regexp.MustCompile(`\{(\w+)\}`)
+
+// placeholder is a value a command can ask for with {name}. When the command
+// runs the user picks one of the candidates in the fuzzy finder. Candidates
+// are lines whose first field is the value substituted, the rest describes it.
+// Sticky placeholders are only asked for once, the value picked is kept for
+// later commands. Others may take the whole line as the value, or let
+// several lines be picked.
+type placeholder struct {
+    name       string
+    what       string
+    sticky     bool
+    wholeLine  bool
+    multiple   bool
+    candidates func(cmd command, picked map[string]string) ([]string, error)
+}
+
+// placeholders are the built-in placeholders, in the order they are asked
+// for when a command uses several.
+var placeholders = []placeholder{
+    {name: "container", what: "running containers", candidates: dockerContainers},
+    {name: "git_branch", what: "git branches", candidates: gitBranches},
+    {name: "git_ref", what: "git branches or tags", candidates: gitRefs},
+    {name: "context", what: "Kubernetes contexts", sticky: true, candidates: kubeContexts},
+    {name: "namespace", what: "Kubernetes namespaces", sticky: true, candidates: kubeNamespaces},
+}
+
+// placeholderDone is what happens to a command once all its placeholders
+// are filled in.
+type placeholderDone func(m *model, cmd command) tea.Cmd
+
+// placeholderPickedMsg is sent when the user has picked a value for a
+// placeholder, or gave up.
+type placeholderPickedMsg struct {
+    cmd         command
+    placeholder placeholder
+    value       string
+    err         error
+    then        placeholderDone
+}
+
+// pickerCommand runs the fuzzy finder over a placeholder's candidates while
+// the TUI is suspended. It implements tea.ExecCommand.
+type pickerCommand struct {
+    cmd         command
+    placeholder placeholder
+    picked      map[string]string
+    value       string   // The first value picked
+    values      []string // Every value picked, for placeholders that allow several
+}
+
+func (p *pickerCommand) Run() error {
+    lines, err := p.placeholder.candidates(p.cmd, p.picked)
+    if err != nil {
+        return err
+    }
+    if len(lines) == 0 {
+        return fmt.Errorf("no %s", p.placeholder.what)
+    }
+    find := func() ([]int, error) {
+        idx, err := fuzzyfinder.Find(lines, func(i int) string {
+            return lines[i]
+        })
+        return []int{idx}, err
+    }
+    if p.placeholder.multiple {
+        // Tab marks lines, enter picks them
+        find = func() ([]int, error) {
+            return fuzzyfinder.FindMulti(lines, func(i int) string {
+                return lines[i]
+            })
+        }
+    }
+    indexes, err := find()
+    if err != nil {
+        return err
+    }
+    p.values = nil
+    for _, idx := range indexes {
+        value := lines[idx]
+        if !p.placeholder.wholeLine {
+            value = strings.Fields(value)[0]
+        }
+        p.values = append(p.values, value)
+    }
+    p.value = p.values[0]
+    return nil
+}
+
+// The fuzzy finder opens the terminal itself.
+func (p *pickerCommand) SetStdin(io.Reader)  {}
+func (p *pickerCommand) SetStdout(io.Writer) {}
+func (p *pickerCommand) SetStderr(io.Writer) {}
+
+// nextPlaceholder returns the next built-in placeholder to fill in cmd's
+// arguments, and false when there is none left.
+func (cmd command) nextPlaceholder() (placeholder, bool) {
+    used := make(map[string]bool)
+    for _, arg := range cmd.cmd {
+        for _, match := range placeholderRef.FindAllStringSubmatch(arg, -1) {
+            used[match[1]] = true
+        }
+    }
+    for _, p := range placeholders {
+        if used[p.name] {
+            return p, true
+        }
+    }
+    return placeholder{}, false
+}
+
+// fill replaces every {name} in cmd's arguments with value.
+func (cmd command) fill(name, value string) command {
+    argv := make([]string, len(cmd.cmd))
+    for i, arg := range cmd.cmd {
+        argv[i] = strings.ReplaceAll(arg, "{"+name+"}", value)
+    }
+    cmd.cmd = argv
+    return cmd
+}
+
+// fillPlaceholders fills in captured variables and then each placeholder in
+// cmd in turn, using the value kept for sticky ones or asking the user, and
+// hands the result to then.
+func (m *model) fillPlaceholders(cmd command, then placeholderDone) tea.Cmd {
+    cmd, err := m.fillVariables(cmd)
+    if err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Cannot run %s: %v\n", cmd.name, err))
+        return nil
+    }
+    for {
+        p, ok := cmd.nextPlaceholder()
+        if !ok {
+            return then(m, cmd)
+        }
+        if value, ok := m.picked[p.name]; ok && p.sticky {
+            cmd = cmd.fill(p.name, value)
+            continue
+        }
+
+        picker := &pickerCommand{cmd: cmd, placeholder: p, picked: m.picked}
+        return tea.Exec(picker, func(err error) tea.Msg {
+            return placeholderPickedMsg{cmd: cmd, placeholder: p, value: picker.value, err: err, then: then}
+        })
+    }
+}
+
+// runWith returns a placeholderDone that runs the command followed by args.
+func runWith(args []string) placeholderDone {
+    return func(m *model, cmd command) tea.Cmd {
+        cmd.cmd = append(cmd.cmd, args...)
+        return m.runCommand(cmd)
+    }
+}
+
+// placeholderPicked carries on with a command once a placeholder's value
+// has been picked.
+func (m *model) placeholderPicked(msg placeholderPickedMsg) tea.Cmd {
+    if errors.Is(msg.err, fuzzyfinder.ErrAbort) {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Cancelled %s\n", msg.cmd.name))
+        return nil
+    }
+    name := msg.placeholder.name
+    if msg.err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error picking {%s} for %s: %v\n", name, msg.cmd.name, msg.err))
+        return m.alertFailure(m.currentTab)
+    }
+    if msg.placeholder.sticky {
+        m.picked[name] = msg.value
+    }
+    return m.fillPlaceholders(msg.cmd.fill(name, msg.value), msg.then)
+}
+
+// dockerContainers lists the running containers, on the command's target
+// when it has one.
+func dockerContainers(cmd command, _ map[string]string) ([]string, error) {
+    return listCandidates(cmd, "docker", "ps", "--format", "{{.ID}}  {{.Names}}  {{.Image}}  {{.Status}}")
+}
+
+// gitRefFormat shows each ref with when it last changed and its subject,
+// most recent first.
+var gitRefFormat = []string{"git", "for-each-ref", "--sort=-committerdate", "--format=%(refname:short)  %(committerdate:relative)  %(subject)"}
+
+// gitBranches lists the local branches of the repository the command runs in.
+func gitBranches(cmd command, _ map[string]string) ([]string, error) {
+    return listCandidates(cmd, append(gitRefFormat, "refs/heads")...)
+}
+
+// gitRefs lists the local and remote branches and the tags of the repository
+// the command runs in.
+func gitRefs(cmd command, _ map[string]string) ([]string, error) {
+    return listCandidates(cmd, append(gitRefFormat, "refs/heads", "refs/remotes", "refs/tags")...)
+}
+
+// listCandidates runs argv where cmd would run and returns the non-empty
+// lines it prints.
+func listCandidates(cmd command, argv ...string) ([]string, error) {
+    cmd.cmd = argv
+    cmd.pty, cmd.interactive = false, false
+    cmd = cmd.remote()
+    out, err := cmd.prepare(cmd.cmd).Output()
+    if err != nil {
+        var exitErr *exec.ExitError
+        if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+            return nil, fmt.Errorf("%s: %s", strings.Join(argv, " "), strings.TrimSpace(string(exitErr.Stderr)))
+        }
+        return nil, err
+    }
+    var lines []string
+    for _, line := range strings.Split(string(out), "\n") {
+        if strings.TrimSpace(line) != "" {
+            lines = append(lines, line)
+        }
+    }
+    return lines, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+    "fmt"
+)
+
+// startPipe asks for a command to pipe the selected button's output into.
+func (m *model) startPipe() {
+    idx, ok := m.selectedIndex()
+    if !ok {
+        return
+    }
+    cmd := m.commands[idx]
+    if cmd.prompt {
+        m.appendOutput(m.currentTab, fmt.Sprintf("%s asks for an argument and cannot be piped\n", cmd.name))
+        return
+    }
+    m.pipeSource = &cmd
+    m.input.SetValue("")
+    m.input.Placeholder = fmt.Sprintf("Pipe the output of %s into...", cmd.name)
+    m.focus = focusInput
+    m.suggestions = nil
+}
@@ -0,0 +1,60 @@
+//go:build !windows
+
+package main
+
+import (
+    "os/exec"
+    "syscall"
+)
+
+// setProcessGroup starts c in a process group of its own, so stopProcess
+// reaches everything it starts, such as a server launched by a script.
+func setProcessGroup(c *exec.Cmd) {
+    if c.SysProcAttr == nil {
+        c.SysProcAttr = &syscall.SysProcAttr{}
+    }
+    c.SysProcAttr.Setpgid = true
+}
+
+// setTerminalOnStdout makes the pseudo-terminal c starts on its controlling
+// terminal through standard output, for commands that read their standard
+// input from elsewhere.
+func setTerminalOnStdout(c *exec.Cmd) {
+    if c.SysProcAttr == nil {
+        c.SysProcAttr = &syscall.SysProcAttr{}
+    }
+    c.SysProcAttr.Ctty = 1
+}
+
+// jobSignals are the signals the signal menu offers.
+var jobSignals = []jobSignal{
+    {"TERM", syscall.SIGTERM, "ask to terminate"},
+    {"INT", syscall.SIGINT, "interrupt, like ctrl+c"},
+    {"HUP", syscall.SIGHUP, "hang up, many daemons reload their config"},
+    {"USR1", syscall.SIGUSR1, "user defined"},
+    {"USR2", syscall.SIGUSR2, "user defined"},
+    {"KILL", syscall.SIGKILL, "kill at once"},
+}
+
+// stopProcess asks c's process group to terminate, or kills it when force
+// is set.
+func stopProcess(c *exec.Cmd, force bool) {
+    sig := syscall.SIGTERM
+    if force {
+        sig = syscall.SIGKILL
+    }
+    signalProcess(c, sig)
+}
+
+// signalProcess sends sig to c's process group.
+func signalProcess(c *exec.Cmd, sig syscall.Signal) error {
+    if c.Process == nil {
+        return nil
+    }
+    // Commands on a pseudo-terminal lead their own session, which makes
+    // them process group leaders as well
+    if err := syscall.Kill(-c.Process.Pid, sig); err != nil {
+        return c.Process.Signal(sig)
+    }
+    return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+    "os/exec"
+    "syscall"
+)
+
+// jobSignals are the signals the signal menu offers. Windows can only kill.
+var jobSignals = []jobSignal{
+    {"KILL", syscall.SIGKILL, "kill at once"},
+}
+
+// setProcessGroup does nothing on Windows, where processes have no groups
+// to signal.
+func setProcessGroup(c *exec.Cmd) {}
+
+// setTerminalOnStdout does nothing on Windows, which has no pseudo-terminals.
+func setTerminalOnStdout(c *exec.Cmd) {}
+
+// stopProcess kills c. Windows has no signal asking a process to terminate.
+func stopProcess(c *exec.Cmd, force bool) {
+    signalProcess(c, syscall.SIGKILL)
+}
+
+// signalProcess kills c, whatever sig is.
+func signalProcess(c *exec.Cmd, sig syscall.Signal) error {
+    if c.Process == nil {
+        return nil
+    }
+    return c.Process.Kill()
+}
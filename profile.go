@@ -0,0 +1,72 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// profile is a named set of buttons and completions that can be switched to
+// while cmdtui runs. Its buttons are listed after the ones shared by every
+// profile, and its completions replace the shared ones when set.
+type profile struct {
+    name        string
+    commands    []command
+    completions completionSource
+}
+
+// extractProfiles reads the profiles table, ordered by name.
+func extractProfiles(node configNode) []profile {
+    names := node.keys()
+    sort.Strings(names)
+
+    profiles := make([]profile, 0, len(names))
+    for _, name := range names {
+        p := node.key(name)
+        p.table()
+        profiles = append(profiles, profile{
+            name:        name,
+            commands:    extractCommands(p.key("buttons")),
+            completions: extractCompletions(p.key("completions")),
+        })
+    }
+    return profiles
+}
+
+// findProfile returns the index of the profile called name, or -1.
+func findProfile(profiles []profile, name string) int {
+    for i, p := range profiles {
+        if p.name == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// useProfile makes profiles[i] the active profile, or none for -1, and
+// rebuilds the button list.
+func (m *model) useProfile(i int) tea.Cmd {
+    m.profile = i
+    m.completions = m.sharedCompletions
+    if i >= 0 && m.profiles[i].completions.isSet() {
+        m.completions = m.profiles[i].completions
+    }
+    m.list.Title = m.listTitle()
+    // A prompt in progress refers to a button by position
+    m.prompInput = false
+    m.suggestions = nil
+    cmd := m.sortCommands()
+    m.watchButtonPaths()
+    return cmd
+}
+
+// nextProfile switches to the profile after the active one.
+func (m *model) nextProfile() tea.Cmd {
+    if len(m.profiles) == 0 {
+        return nil
+    }
+    cmd := m.useProfile((m.profile + 1) % len(m.profiles))
+    m.appendOutput(m.currentTab, fmt.Sprintf("Switched to profile %s\n", m.profiles[m.profile].name))
+    return cmd
+}
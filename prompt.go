@@ -0,0 +1,265 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "slices"
+    "strings"
+
+    "github.com/charmbracelet/bubbles/key"
+    "github.com/charmbracelet/bubbles/textinput"
+    tea "github.com/charmbracelet/bubbletea"
+    lua "github.com/yuin/gopher-lua"
+)
+
+// promptSpec describes how a button asks for its argument. prompt may be
+// true, or a table with a label and a validate rule, either a pattern the
+// argument must match or, in Lua configs, a function returning false or an
+// error message for bad arguments. Instead of typing it, the argument can be
+// picked from a list of choices, or several of them with multiple set. A
+// type of number or time asks for a number within bounds or a date instead.
+type promptSpec struct {
+    kind     string         // The type of the prompt: "text", the default, "number" or "time"
+    number   numberSpec     // The bounds of a number prompt
+    time     timeSpec       // The format of a time prompt
+    label    string         // Shown in the empty input box
+    pattern  *regexp.Regexp // The argument must match, if set
+    validate *lua.LFunction // Checks the argument, if set
+    choices  []string       // The only arguments allowed, picked in the fuzzy finder
+    multiple bool           // Several choices can be picked, each passed as an argument
+    join     string         // Pass the choices picked as one argument joined by this, if set
+    flag     string         // Pass each choice picked after this flag, if set
+    params   []string       // Names of the task parameters the argument fills, for imported tasks
+    assign   bool           // Pass the parameters as NAME=value rather than in order
+}
+
+// extractPrompt reads a button's prompt setting and reports whether the
+// button prompts at all.
+func extractPrompt(node configNode) (bool, promptSpec) {
+    if _, ok := node.value.(map[string]interface{}); !ok {
+        return node.boolean(false), promptSpec{}
+    }
+    spec := promptSpec{
+        kind:     node.key("type").str(""),
+        label:    node.key("label").str(""),
+        choices:  node.key("choices").strs(),
+        multiple: node.key("multiple").boolean(false),
+        join:     node.key("join").str(""),
+        flag:     node.key("flag").str(""),
+    }
+    switch spec.kind {
+    case "", "text":
+    case "number":
+        spec.number = extractNumber(node)
+    case "time":
+        spec.time = extractTime(node)
+    default:
+        node.key("type").fail("unknown prompt type %q, expected one of text, number, time", spec.kind)
+    }
+    if choices := node.key("choices"); choices.isSet() && len(spec.choices) == 0 {
+        choices.fail("must not be empty")
+    }
+    if spec.multiple && len(spec.choices) == 0 {
+        node.key("multiple").fail("needs choices to pick from")
+    }
+    if fn, ok := node.key("validate").value.(*lua.LFunction); ok {
+        spec.validate = fn
+    } else {
+        spec.pattern = node.key("validate").regexp()
+    }
+    return true, spec
+}
+
+// args returns the arguments to add to the command for the value typed at
+// the prompt. Imported tasks with parameters take one argument per word, and
+// with assign set words without an = are given the next parameter's name.
+func (spec promptSpec) args(value string) []string {
+    if len(spec.params) == 0 {
+        return []string{value}
+    }
+    words := strings.Fields(value)
+    if spec.assign {
+        for i, word := range words {
+            if !strings.Contains(word, "=") && i < len(spec.params) {
+                words[i] = spec.params[i] + "=" + word
+            }
+        }
+    }
+    return words
+}
+
+// choiceArgs returns the arguments to add to the command for the choices
+// picked: one each, each after the flag, or all joined into one.
+func (spec promptSpec) choiceArgs(values []string) []string {
+    if spec.join != "" {
+        return []string{strings.Join(values, spec.join)}
+    }
+    var args []string
+    for _, value := range values {
+        if spec.flag != "" {
+            args = append(args, spec.flag)
+        }
+        args = append(args, spec.args(value)...)
+    }
+    return args
+}
+
+// start returns the argument the prompt starts with, "" for text.
+func (spec promptSpec) start() string {
+    switch spec.kind {
+    case "number":
+        return spec.number.format(spec.number.initial)
+    case "time":
+        return spec.time.start()
+    }
+    return ""
+}
+
+// askArgument focuses the input so the user can type the argument for the
+// button at idx, or has it picked from the button's choices.
+func (m *model) askArgument(idx int) tea.Cmd {
+    cmd := m.commands[idx]
+    if len(cmd.promptSpec.choices) > 0 {
+        return m.pickChoice(cmd)
+    }
+    m.input.SetValue(cmd.promptSpec.start())
+    m.input.Placeholder = fmt.Sprintf("Argument for %s...", cmd.name)
+    if cmd.promptSpec.label != "" {
+        m.input.Placeholder = cmd.promptSpec.label
+    }
+    m.input.Focus()
+    m.focus = focusInput
+    m.prompInput = true
+    m.currentIndex = idx
+    m.suggestions = nil
+    m.promptError = ""
+    return nil
+}
+
+// prompted returns the spec of the argument being typed at the prompt.
+func (m *model) prompted() (promptSpec, bool) {
+    if !m.prompInput || m.currentIndex < 0 || m.currentIndex >= len(m.commands) {
+        return promptSpec{}, false
+    }
+    return m.commands[m.currentIndex].promptSpec, true
+}
+
+// stepDirection returns which way key steps the argument being typed, 1 up
+// and -1 down, or 0 when it is typed as usual. Numbers step with + and - as
+// well as the arrows, times only with the arrows since dates are typed with
+// a -.
+func (m *model) stepDirection(key string) int {
+    spec, ok := m.prompted()
+    if !ok || spec.kind != "number" && spec.kind != "time" {
+        return 0
+    }
+    switch {
+    case key == "up" || key == "+" && spec.kind == "number":
+        return 1
+    case key == "down" || key == "-" && spec.kind == "number":
+        return -1
+    }
+    return 0
+}
+
+// stepArgument moves the number or time typed at the prompt up or down by
+// a step.
+func (m *model) stepArgument(dir int) {
+    spec, _ := m.prompted()
+    value := m.input.Value()
+    switch spec.kind {
+    case "number":
+        value = spec.number.stepped(value, dir)
+    case "time":
+        value = spec.time.stepped(value, dir)
+    }
+    m.input.SetValue(value)
+    m.input.CursorEnd()
+    m.promptError = ""
+}
+
+// choicePicker returns the picker for the argument of a button with choices.
+// The pseudo command it picks for has the value as its only argument.
+func choicePicker(cmd command) *pickerCommand {
+    what := "choices for " + cmd.name
+    if cmd.promptSpec.label != "" {
+        what = cmd.promptSpec.label
+    }
+    choices := cmd.promptSpec.choices
+    p := placeholder{name: "choice", what: what, wholeLine: true, multiple: cmd.promptSpec.multiple, candidates: func(command, map[string]string) ([]string, error) {
+        return choices, nil
+    }}
+    return &pickerCommand{cmd: command{name: cmd.name, cmd: []string{"{choice}"}}, placeholder: p}
+}
+
+// pickChoice asks for cmd's choices in the fuzzy finder and runs cmd with
+// them.
+func (m *model) pickChoice(cmd command) tea.Cmd {
+    picker := choicePicker(cmd)
+    return tea.Exec(picker, func(err error) tea.Msg {
+        return placeholderPickedMsg{cmd: picker.cmd, placeholder: picker.placeholder, value: picker.value, err: err, then: func(m *model, _ command) tea.Cmd {
+            cmd.prompt = false
+            m.noteUse(cmd.name)
+            return tea.Batch(m.runButton(cmd, cmd.promptSpec.choiceArgs(picker.values)...), m.sortCommands())
+        }}
+    })
+}
+
+// checkArgument returns why value is not a valid argument for spec, or ""
+// when it is.
+func (m *model) checkArgument(spec promptSpec, value string) string {
+    if len(spec.choices) > 0 && !slices.Contains(spec.choices, value) {
+        return "must be one of " + strings.Join(spec.choices, ", ")
+    }
+    switch spec.kind {
+    case "number":
+        if problem := spec.number.check(value); problem != "" {
+            return problem
+        }
+    case "time":
+        if problem := spec.time.check(value); problem != "" {
+            return problem
+        }
+    }
+    if spec.pattern != nil && !spec.pattern.MatchString(value) {
+        return fmt.Sprintf("must match %s", spec.pattern)
+    }
+    if spec.validate != nil && m.script != nil {
+        problem, err := m.script.validate(spec.validate, value)
+        if err != nil {
+            return fmt.Sprintf("validate failed: %v", err)
+        }
+        return problem
+    }
+    return ""
+}
+
+// resetInput puts the input back to typing free-form commands.
+func (m *model) resetInput() {
+    m.input.SetValue("")
+    m.input.Placeholder = "Type a command..."
+    m.suggestions = nil
+    m.promptError = ""
+    m.pipeSource = nil
+    m.searching = false
+    m.exporting = false
+    m.confirming = false
+    m.passwordJob = 0
+    m.input.EchoMode = textinput.EchoNormal
+    m.viNormal, m.viPending, m.viUndo = false, "", nil
+}
+
+// inputTakes reports whether msg goes to the input while it has focus, ahead
+// of the global bindings: keys that type a character, so q or ] can be
+// typed, and the readline keys the input edits with, such as ctrl+a, ctrl+e,
+// alt+b, alt+f, ctrl+w and ctrl+k. Bindings the config sets to one of them
+// still work from the list and output.
+func (m *model) inputTakes(msg tea.KeyMsg) bool {
+    if (msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace) && !msg.Alt {
+        return true
+    }
+    k := m.input.KeyMap
+    return key.Matches(msg, k.CharacterForward, k.CharacterBackward, k.WordForward, k.WordBackward,
+        k.DeleteWordBackward, k.DeleteWordForward, k.DeleteAfterCursor, k.DeleteBeforeCursor,
+        k.DeleteCharacterBackward, k.DeleteCharacterForward, k.LineStart, k.LineEnd, k.Paste)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+    "fmt"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// quit exits, first asking whether to stop the commands still running.
+func (m *model) quit() tea.Cmd {
+    if len(m.jobs) == 0 {
+        return tea.Quit
+    }
+    m.confirmingQuit = true
+    return nil
+}
+
+// confirmQuit handles the answer to the question asked by quit: y or enter
+// stops the running commands and exits, any other key carries on.
+func (m *model) confirmQuit(msg tea.KeyMsg) tea.Cmd {
+    m.confirmingQuit = false
+    switch msg.String() {
+    case "y", "Y", "enter":
+        m.killJobs()
+        return tea.Quit
+    }
+    return nil
+}
+
+// quitQuestion is shown in the status line while quit waits for an answer.
+func (m *model) quitQuestion() string {
+    jobs := "1 job is"
+    if len(m.jobs) != 1 {
+        jobs = fmt.Sprintf("%d jobs are", len(m.jobs))
+    }
+    return errorStyle.Render(fmt.Sprintf("%s still running, kill them and quit? (y/n)", jobs))
+}
+
+// killGrace is how long commands get to exit after being asked to
+// terminate before they are killed.
+const killGrace = time.Second
+
+// killJobs stops every running command, along with the processes they
+// started.
+func (m *model) killJobs() {
+    for _, j := range m.jobs {
+        j.stop(false)
+    }
+    deadline := time.Now().Add(killGrace)
+    for _, j := range m.jobs {
+        select {
+        case <-j.exited:
+        case <-time.After(time.Until(deadline)):
+            j.stop(true)
+        }
+    }
+}
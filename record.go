@@ -0,0 +1,90 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+    "strings"
+    "time"
+
+    "github.com/creack/pty"
+)
+
+// recorder writes the output shown in the tabs to a file in asciinema's
+// asciicast v2 format, with a marker where each command starts, so a
+// session can be played back with asciinema or replayed in cmdtui.
+type recorder struct {
+    f       *os.File
+    enc     *json.Encoder
+    started time.Time
+    err     error // The first write that failed, nothing more is written after it
+}
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+    Version   int               `json:"version"`
+    Width     int               `json:"width"`
+    Height    int               `json:"height"`
+    Timestamp int64             `json:"timestamp"`
+    Title     string            `json:"title,omitempty"`
+    Env       map[string]string `json:"env,omitempty"`
+}
+
+// newRecorder creates path and starts recording to it. The recording is as
+// wide and tall as the terminal.
+func newRecorder(path string) (*recorder, error) {
+    f, err := os.Create(path)
+    if err != nil {
+        return nil, err
+    }
+    rows, cols, err := pty.Getsize(os.Stdout)
+    if err != nil || rows == 0 || cols == 0 {
+        rows, cols = 24, 80
+    }
+    r := &recorder{f: f, enc: json.NewEncoder(f), started: time.Now()}
+    r.enc.SetEscapeHTML(false)
+    r.write(castHeader{
+        Version:   2,
+        Width:     cols,
+        Height:    rows,
+        Timestamp: r.started.Unix(),
+        Title:     "cmdtui",
+        Env:       map[string]string{"SHELL": os.Getenv("SHELL"), "TERM": os.Getenv("TERM")},
+    })
+    if r.err != nil {
+        f.Close()
+        return nil, r.err
+    }
+    return r, nil
+}
+
+// write adds a line of JSON to the recording.
+func (r *recorder) write(v any) {
+    if r.err != nil {
+        return
+    }
+    r.err = r.enc.Encode(v)
+}
+
+// output records text being shown. Lines end in \r\n, as on a terminal.
+func (r *recorder) output(text string) {
+    text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+    r.write([]any{r.elapsed(), "o", text})
+}
+
+// marker records that a command started, named by its command line.
+func (r *recorder) marker(label string) {
+    r.write([]any{r.elapsed(), "m", label})
+}
+
+// elapsed returns the seconds since the recording started.
+func (r *recorder) elapsed() float64 {
+    return time.Since(r.started).Round(time.Microsecond).Seconds()
+}
+
+// close finishes the recording, returning the first error writing it.
+func (r *recorder) close() error {
+    if err := r.f.Close(); r.err == nil {
+        r.err = err
+    }
+    return r.err
+}
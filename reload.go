@@ -0,0 +1,127 @@
+package main
+
+import (
+    "path/filepath"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/fsnotify/fsnotify"
+)
+
+// reloadDebounce lets editors finish writing before the config is re-read,
+// many of them emit several events for a single save.
+const reloadDebounce = 150 * time.Millisecond
+
+// configReloadedMsg carries the result of re-reading the config file.
+type configReloadedMsg struct {
+    cfg config
+    err error
+}
+
+// configWatcher re-reads the config file whenever it, or a file it
+// includes, changes on disk, or a file is added to a script directory.
+type configWatcher struct {
+    path    string
+    watcher *fsnotify.Watcher
+    events  chan tea.Msg
+    files   map[string]bool // Cleaned paths of the files the config was read from
+    dirs    map[string]bool // Cleaned paths of the script directories
+    now     chan struct{}   // Reloads without waiting for a change, see reloadNow
+}
+
+// watchConfig starts watching the files cfg was read from, the first of
+// which is the config itself. The containing directories are watched rather
+// than the files so saves that replace a file are seen too.
+func watchConfig(cfg config) (*configWatcher, error) {
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        return nil, err
+    }
+
+    w := &configWatcher{path: cfg.files[0], watcher: watcher, events: make(chan tea.Msg), now: make(chan struct{}, 1)}
+    if err := w.watch(cfg); err != nil {
+        watcher.Close()
+        return nil, err
+    }
+    go w.run()
+    return w, nil
+}
+
+// watch replaces the set of files and directories that trigger a reload.
+func (w *configWatcher) watch(cfg config) error {
+    w.files = make(map[string]bool)
+    for _, file := range cfg.files {
+        w.files[filepath.Clean(file)] = true
+        // Adding a directory twice is harmless
+        if err := w.watcher.Add(filepath.Dir(file)); err != nil {
+            return err
+        }
+    }
+    w.dirs = make(map[string]bool)
+    for _, dir := range cfg.dirs {
+        w.dirs[filepath.Clean(dir)] = true
+        if err := w.watcher.Add(dir); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (w *configWatcher) run() {
+    var debounce <-chan time.Time
+    for {
+        select {
+        case event, ok := <-w.watcher.Events:
+            if !ok {
+                return
+            }
+            name := filepath.Clean(event.Name)
+            if !(w.files[name] || w.dirs[filepath.Dir(name)]) || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove|fsnotify.Chmod) {
+                continue
+            }
+            debounce = time.After(reloadDebounce)
+        case <-w.now:
+            debounce = time.After(0)
+        case <-debounce:
+            debounce = nil
+            cfg, err := loadConfig(w.path)
+            if err == nil {
+                // Pick up files that were included or dropped
+                w.watch(cfg)
+            }
+            w.events <- configReloadedMsg{cfg: cfg, err: err}
+        case _, ok := <-w.watcher.Errors:
+            if !ok {
+                return
+            }
+        }
+    }
+}
+
+// reloadNow asks for the config to be read again even though nothing
+// changed, e.g. to pick up new Makefile targets. A reload already asked for
+// covers this one too.
+func (w *configWatcher) reloadNow() {
+    select {
+    case w.now <- struct{}{}:
+    default:
+    }
+}
+
+// reloadButtons reads the config again, which imports the Makefile and
+// scripts again and runs the generators. Without a watcher only the
+// generators run.
+func (m *model) reloadButtons() tea.Cmd {
+    if m.watcher == nil {
+        return m.generate()
+    }
+    m.watcher.reloadNow()
+    return nil
+}
+
+// wait returns a tea.Cmd that delivers the next reload result.
+func (w *configWatcher) wait() tea.Cmd {
+    return func() tea.Msg {
+        return <-w.events
+    }
+}
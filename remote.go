@@ -0,0 +1,61 @@
+package main
+
+import (
+    "strings"
+)
+
+// extractTargets reads the targets table, which names the hosts buttons
+// can run on, e.g. targets = { prod = "deploy@prod.example.com" }.
+func extractTargets(node configNode) map[string]string {
+    targets := make(map[string]string)
+    for _, name := range node.keys() {
+        targets[name] = node.key(name).requiredStr()
+    }
+    return targets
+}
+
+// shellQuote quotes s so a POSIX shell reads it as a single word.
+func shellQuote(s string) string {
+    if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@,+%") == "" {
+        return s
+    }
+    return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// shellLine returns cmd as a line for a POSIX shell, which changes to its
+// working directory and sets its environment before running it.
+func (cmd command) shellLine() string {
+    var script []string
+    if cmd.dir != "" {
+        script = append(script, "cd", shellQuote(cmd.dir), "&&")
+    }
+    if len(cmd.env) > 0 {
+        script = append(script, "env")
+        for _, pair := range cmd.env {
+            script = append(script, shellQuote(pair))
+        }
+    }
+    for _, arg := range cmd.cmd {
+        script = append(script, shellQuote(arg))
+    }
+    return strings.Join(script, " ")
+}
+
+// remote rewrites cmd to run on its target over ssh. The working directory
+// and environment are applied on the remote side, the local ssh runs with
+// neither. Commands without a target are returned unchanged.
+func (cmd command) remote() command {
+    if cmd.target == "" {
+        return cmd
+    }
+
+    argv := []string{"ssh"}
+    if cmd.pty || cmd.interactive {
+        // Ask for a terminal on the remote side too
+        argv = append(argv, "-t")
+    }
+    cmd.cmd = append(argv, cmd.target, cmd.shellLine())
+    cmd.dir = ""
+    cmd.env = nil
+    return cmd
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "regexp"
+    "sort"
+    "strings"
+
+    "github.com/charmbracelet/lipgloss"
+    "github.com/charmbracelet/lipgloss/table"
+)
+
+// renderer formats a command's complete output for the viewport. Buttons
+// pick one by name with render = "json", new formats only need an entry in
+// renderers.
+type renderer interface {
+    // render formats output to fit width columns.
+    render(output string, width int) (string, error)
+}
+
+// lineRenderer is implemented by renderers that can format output a line at
+// a time, so it is shown as it arrives instead of when the command exits.
+type lineRenderer interface {
+    renderer
+    renderLine(line string) string
+}
+
+// renderers are the output formats buttons can choose from.
+var renderers = map[string]renderer{
+    "raw":      rawRenderer{},
+    "ansi":     ansiRenderer{},
+    "json":     jsonRenderer{},
+    "table":    tableRenderer{},
+    "markdown": markdownRenderer{},
+}
+
+// extractRenderer reads a button's render setting, nil when unset.
+func extractRenderer(node configNode) renderer {
+    name := node.str("")
+    if name == "" {
+        return nil
+    }
+    r, ok := renderers[name]
+    if !ok {
+        names := make([]string, 0, len(renderers))
+        for n := range renderers {
+            names = append(names, n)
+        }
+        sort.Strings(names)
+        node.fail("unknown renderer %q, expected one of %s", name, strings.Join(names, ", "))
+    }
+    return r
+}
+
+// capturesOutput reports whether cmd's output is held back until it exits,
+// because its transform or renderer needs all of it.
+func (cmd command) capturesOutput() bool {
+    if cmd.transform != nil {
+        return true
+    }
+    _, streams := cmd.renderer.(lineRenderer)
+    return cmd.renderer != nil && !streams
+}
+
+// renderLine formats a single line of output from a command that doesn't
+// capture its output.
+func (cmd command) renderLine(line string) string {
+    if lr, ok := cmd.renderer.(lineRenderer); ok {
+        return lr.renderLine(line)
+    }
+    return line
+}
+
+// renderOutput runs the captured output of cmd through its transform and
+// renderer. Output the renderer can't make sense of is shown unchanged,
+// followed by the reason.
+func (m *model) renderOutput(cmd command, output string, width int) string {
+    if cmd.transform != nil {
+        output = m.transformOutput(cmd.transform, output)
+    }
+    if cmd.renderer == nil {
+        return output
+    }
+    rendered, err := cmd.renderer.render(output, width)
+    if err != nil {
+        return output + fmt.Sprintf("Error rendering output: %v\n", err)
+    }
+    if rendered != "" && !strings.HasSuffix(rendered, "\n") {
+        rendered += "\n"
+    }
+    return rendered
+}
+
+// ansiEscape matches terminal escape sequences: CSI sequences such as colors
+// and cursor movement, and OSC sequences such as window titles and links.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// rawRenderer shows output as plain text, with escape sequences removed.
+type rawRenderer struct{}
+
+func (rawRenderer) render(output string, _ int) (string, error) {
+    return ansiEscape.ReplaceAllString(output, ""), nil
+}
+
+func (rawRenderer) renderLine(line string) string {
+    return ansiEscape.ReplaceAllString(line, "")
+}
+
+// ansiRenderer shows output as it is, colors included. It is what buttons
+// without a renderer get, and can be named to be explicit.
+type ansiRenderer struct{}
+
+func (ansiRenderer) render(output string, _ int) (string, error) {
+    return output, nil
+}
+
+func (ansiRenderer) renderLine(line string) string {
+    return line
+}
+
+// jsonRenderer pretty prints JSON output. Several values in a row, such as
+// JSON lines, are each printed in turn.
+type jsonRenderer struct{}
+
+func (jsonRenderer) render(output string, _ int) (string, error) {
+    dec := json.NewDecoder(strings.NewReader(output))
+    var b strings.Builder
+    for {
+        var value json.RawMessage
+        if err := dec.Decode(&value); errors.Is(err, io.EOF) {
+            break
+        } else if err != nil {
+            return "", err
+        }
+        var indented bytes.Buffer
+        if err := json.Indent(&indented, value, "", "  "); err != nil {
+            return "", err
+        }
+        b.Write(indented.Bytes())
+        b.WriteByte('\n')
+    }
+    return b.String(), nil
+}
+
+// tableRenderer draws tabular output as a table, taking the first line as
+// the header. Columns are separated by tabs, or otherwise lined up with
+// spaces like the output of ps, docker ps and kubectl get.
+type tableRenderer struct{}
+
+func (tableRenderer) render(output string, width int) (string, error) {
+    var lines []string
+    for _, line := range strings.Split(ansiEscape.ReplaceAllString(output, ""), "\n") {
+        if strings.TrimSpace(line) != "" {
+            lines = append(lines, line)
+        }
+    }
+    if len(lines) == 0 {
+        return "", nil
+    }
+
+    var rows [][]string
+    if strings.Contains(output, "\t") {
+        for _, line := range lines {
+            rows = append(rows, strings.Split(line, "\t"))
+        }
+    } else {
+        rows = splitColumns(lines)
+    }
+    columns := 0
+    for _, row := range rows {
+        columns = max(columns, len(row))
+    }
+    for i := range rows {
+        for len(rows[i]) < columns {
+            rows[i] = append(rows[i], "")
+        }
+    }
+
+    t := table.New().
+        Border(lipgloss.NormalBorder()).
+        BorderStyle(statusStyle).
+        Headers(rows[0]...).
+        Rows(rows[1:]...).
+        StyleFunc(func(row, _ int) lipgloss.Style {
+            if row == 0 {
+                return tableHeaderStyle
+            }
+            return tableCellStyle
+        })
+    if lipgloss.Width(t.String()) > width {
+        t.Width(width)
+    }
+    return t.String(), nil
+}
+
+// splitColumns cuts lines into cells at the positions that are blank on
+// every line, which is where columns lined up with spaces meet.
+func splitColumns(lines []string) [][]string {
+    runes := make([][]rune, len(lines))
+    width := 0
+    for i, line := range lines {
+        runes[i] = []rune(line)
+        width = max(width, len(runes[i]))
+    }
+    blank := make([]bool, width+1)
+    for i := range blank {
+        blank[i] = true
+        for _, r := range runes {
+            if i < len(r) && r[i] != ' ' {
+                blank[i] = false
+                break
+            }
+        }
+    }
+
+    rows := make([][]string, len(runes))
+    for n, r := range runes {
+        start := -1
+        for i, isBlank := range blank {
+            if !isBlank && start < 0 {
+                start = i
+            } else if isBlank && start >= 0 {
+                rows[n] = append(rows[n], strings.TrimSpace(string(r[min(start, len(r)):min(i, len(r))])))
+                start = -1
+            }
+        }
+    }
+    return rows
+}
+
+var (
+    tableHeaderStyle = lipgloss.NewStyle().Padding(0, 1).Bold(true).Foreground(accentColor)
+    tableCellStyle   = lipgloss.NewStyle().Padding(0, 1)
+)
+
+// markdownRenderer styles Markdown output: headings, emphasis, inline code,
+// code blocks, lists and quotes. Anything else is shown as written.
+type markdownRenderer struct{}
+
+var (
+    markdownHeading = lipgloss.NewStyle().Bold(true).Foreground(accentColor)
+    markdownCode    = lipgloss.NewStyle().Foreground(codeColor)
+    markdownQuote   = lipgloss.NewStyle().Foreground(quoteColor).Italic(true)
+    markdownBold    = lipgloss.NewStyle().Bold(true)
+    markdownItalic  = lipgloss.NewStyle().Italic(true)
+
+    markdownHeadingLine = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+    markdownListItem    = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+    markdownInlineCode  = regexp.MustCompile("`([^`]+)`")
+    markdownStrong      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+    markdownEmphasis    = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+func (markdownRenderer) render(output string, width int) (string, error) {
+    var b strings.Builder
+    inCode := false
+    for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+        trimmed := strings.TrimSpace(line)
+        if strings.HasPrefix(trimmed, "```") {
+            inCode = !inCode
+            continue
+        }
+        switch {
+        case inCode:
+            line = markdownCode.Render("    " + line)
+        case markdownHeadingLine.MatchString(line):
+            match := markdownHeadingLine.FindStringSubmatch(line)
+            line = markdownHeading.Render(inlineMarkdown(match[2]))
+            if len(match[1]) == 1 {
+                line += "\n" + markdownHeading.Render(strings.Repeat("─", min(lipgloss.Width(line), width)))
+            }
+        case strings.HasPrefix(trimmed, ">"):
+            line = markdownQuote.Render("│ " + strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+        case markdownListItem.MatchString(line):
+            match := markdownListItem.FindStringSubmatch(line)
+            line = match[1] + "• " + inlineMarkdown(match[2])
+        case trimmed == "---" || trimmed == "***":
+            line = statusStyle.Render(strings.Repeat("─", width))
+        default:
+            line = inlineMarkdown(line)
+        }
+        b.WriteString(line + "\n")
+    }
+    return b.String(), nil
+}
+
+// inlineMarkdown styles code spans, bold and italic text within a line.
+func inlineMarkdown(line string) string {
+    line = markdownInlineCode.ReplaceAllStringFunc(line, func(s string) string {
+        return markdownCode.Render(s[1 : len(s)-1])
+    })
+    line = markdownStrong.ReplaceAllStringFunc(line, func(s string) string {
+        return markdownBold.Render(s[2 : len(s)-2])
+    })
+    return markdownEmphasis.ReplaceAllStringFunc(line, func(s string) string {
+        return markdownItalic.Render(s[1 : len(s)-1])
+    })
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// replay steps through a session recorded with --record, a command and its
+// output at a time, in the first tab.
+type replay struct {
+    path  string
+    steps []replayStep
+    shown int // How many steps are in the tab
+}
+
+// replayStep is a command of a recorded session with the output that
+// followed it, up to the next command.
+type replayStep struct {
+    label  string
+    output string
+}
+
+// loadReplay reads an asciicast v2 file, splitting its output into steps at
+// the markers --record writes where commands start. Output before the first
+// marker is a step of its own.
+func loadReplay(path string) (*replay, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    scanner := bufio.NewScanner(f)
+    scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+    if !scanner.Scan() {
+        return nil, fmt.Errorf("%s: empty file", path)
+    }
+    var header castHeader
+    if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version != 2 {
+        return nil, fmt.Errorf("%s: not an asciicast v2 recording", path)
+    }
+
+    r := &replay{path: path}
+    for line := 2; scanner.Scan(); line++ {
+        var event []any
+        if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+            return nil, fmt.Errorf("%s:%d: expected an event like [time, type, data]", path, line)
+        }
+        kind, _ := event[1].(string)
+        data, _ := event[2].(string)
+        switch {
+        case kind == "m":
+            r.steps = append(r.steps, replayStep{label: data})
+        case kind == "o":
+            if len(r.steps) == 0 {
+                r.steps = append(r.steps, replayStep{label: "before the first command"})
+            }
+            r.steps[len(r.steps)-1].output += strings.ReplaceAll(data, "\r\n", "\n")
+        }
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+    if len(r.steps) == 0 {
+        return nil, errors.New(path + ": nothing was recorded")
+    }
+    return r, nil
+}
+
+// startReplay shows the hint for stepping through r in the first tab.
+func (m *model) startReplay(r *replay) {
+    m.replay = r
+    m.selectTab(0)
+    t := &m.tabs[0]
+    t.setText("")
+    t.viewport.SetContent(fmt.Sprintf("Replaying %s, press %s for the first of its %d steps",
+        r.path, m.keys.ReplayNext.Help().Key, len(r.steps)))
+}
+
+// stepReplay shows the next step of the replay, or takes the last one away
+// when dir is negative.
+func (m *model) stepReplay(dir int) {
+    r := m.replay
+    r.shown = min(max(r.shown+dir, 0), len(r.steps))
+    var b strings.Builder
+    for _, step := range r.steps[:r.shown] {
+        b.WriteString(step.output)
+    }
+    t := &m.tabs[0]
+    t.setText(b.String())
+    t.showOutput()
+    t.viewport.GotoBottom()
+}
+
+// replayStatus describes the replay for the status line.
+func (m *model) replayStatus() string {
+    r := m.replay
+    if r.shown == 0 {
+        return fmt.Sprintf("Replay: 0 of %d steps", len(r.steps))
+    }
+    return fmt.Sprintf("Replay: %d of %d, %s", r.shown, len(r.steps), r.steps[r.shown-1].label)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/charmbracelet/lipgloss"
+)
+
+var (
+    runHeaderStyle = lipgloss.NewStyle().Bold(true).Foreground(accentColor)
+    runSuccess     = lipgloss.NewStyle().Foreground(successColor)
+    runFailure     = lipgloss.NewStyle().Foreground(failureColor).Bold(true)
+    runCurrent     = runHeaderStyle.Copy().Reverse(true)
+)
+
+// runHeader returns the line that starts the output of a run of line, with
+// the time it started.
+func runHeader(line string, started time.Time) string {
+    return runHeaderStyle.Render(icons.run+" "+line) + statusStyle.Render("  "+started.Format("15:04:05")) + "\n"
+}
+
+// runFooter returns the line that ends the output of a run of the button
+// called name, green when it succeeded and red with the error when it
+// failed, so runs are easy to tell apart when scrolling back.
+func runFooter(name string, err error, elapsed time.Duration) string {
+    if elapsed < time.Second {
+        elapsed = elapsed.Round(time.Millisecond)
+    } else {
+        elapsed = elapsed.Round(100 * time.Millisecond)
+    }
+    if err != nil {
+        return runFailure.Render(fmt.Sprintf("%s %s failed after %s: %v", icons.failure, name, elapsed, err)) + "\n"
+    }
+    return runSuccess.Render(fmt.Sprintf("%s %s finished after %s", icons.success, name, elapsed)) + "\n"
+}
+
+// runStart is where a run begins in the lines of a tab's viewport.
+type runStart struct {
+    line   int
+    header string // The header line without colors, which folds are kept by
+}
+
+// isRunHeader reports whether a line of output starts a run. The headers
+// of runs from before a reload may start with the icon of another set.
+func isRunHeader(line string) bool {
+    line = ansiEscape.ReplaceAllString(line, "")
+    for _, set := range iconSets {
+        if strings.HasPrefix(line, set.run+" ") {
+            return true
+        }
+    }
+    return false
+}
+
+// runOn returns the run the line of the viewport's content belongs to, and
+// false when it comes before the first run.
+func (t *outputTab) runOn(line int) (runStart, bool) {
+    for i := len(t.runs) - 1; i >= 0; i-- {
+        if t.runs[i].line <= line {
+            return t.runs[i], true
+        }
+    }
+    return runStart{}, false
+}
+
+// findRun returns where the run with header starts.
+func (t *outputTab) findRun(header string) (runStart, bool) {
+    for _, r := range t.runs {
+        if r.header == header {
+            return r, true
+        }
+    }
+    return runStart{}, false
+}
+
+// currentRun returns the run the fold key acts on: the one the last jump
+// went to while its header is in view, otherwise the one at the top.
+func (t *outputTab) currentRun() (runStart, bool) {
+    if r, ok := t.findRun(t.atRun); ok && r.line >= t.viewport.YOffset && r.line < t.viewport.YOffset+t.viewport.Height {
+        return r, true
+    }
+    return t.runOn(t.viewport.YOffset)
+}
+
+// jumpRun scrolls the current tab to the start of the next run, or the
+// previous one when dir is negative, counting from the run the last jump
+// went to or else the top of the viewport.
+func (m *model) jumpRun(dir int) {
+    t := &m.tabs[m.currentTab]
+    if m.isCompared(m.currentTab) || len(t.runs) == 0 {
+        return
+    }
+    from := t.viewport.YOffset
+    if r, ok := t.currentRun(); ok && r.header == t.atRun {
+        from = r.line
+    }
+    target, found := runStart{}, false
+    for _, r := range t.runs {
+        if dir > 0 && r.line > from {
+            target, found = r, true
+            break
+        }
+        if dir < 0 && r.line < from {
+            target, found = r, true
+        }
+    }
+    if !found {
+        return
+    }
+    t.atRun = target.header
+    t.showOutput()
+    t.jumpTo(target.line)
+}
+
+// toggleFold folds the current run of the current tab to just its header,
+// or unfolds it. A run that started above the top is scrolled back to its
+// header.
+func (m *model) toggleFold() {
+    t := &m.tabs[m.currentTab]
+    if m.isCompared(m.currentTab) {
+        return
+    }
+    run, ok := t.currentRun()
+    if !ok {
+        return
+    }
+    offset := min(t.viewport.YOffset, run.line)
+    if t.folded == nil {
+        t.folded = make(map[string]bool)
+    }
+    if t.folded[run.header] {
+        delete(t.folded, run.header)
+    } else {
+        t.folded[run.header] = true
+    }
+    t.atRun = run.header
+    t.showOutput()
+    t.viewport.SetYOffset(offset)
+}
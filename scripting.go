@@ -0,0 +1,268 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    tea "github.com/charmbracelet/bubbletea"
+    lua "github.com/yuin/gopher-lua"
+)
+
+// scripting is the Lua state of a Lua config, kept alive after loading so
+// the config can react to what happens while cmdtui runs.
+//
+// The config may define these global hooks:
+//
+//    on_start(cmd)       before a command runs, cmd is {name = ..., cmd = {...}}
+//    on_output(line, name) for every line of output
+//    on_exit(code, name) when a command exits, code is -1 if it never started
+//
+// and can call into cmdtui through the global cmdtui table:
+//
+//    cmdtui.add_button{name = ..., cmd = {...}}  add a button to the list
+//    cmdtui.write(tab, text)                      append text to a tab, by number or title
+//    cmdtui.run(name)                             run the button with the given name
+//
+// The state is only touched from the bubbletea update loop.
+type scripting struct {
+    L       *lua.LState
+    file    string
+    pending []scriptAction
+}
+
+// scriptAction is a change requested through the cmdtui table. Actions are
+// queued while Lua runs and applied to the model once it returns.
+type scriptAction func(m *model) tea.Cmd
+
+func newScripting(file string) *scripting {
+    s := &scripting{L: lua.NewState(), file: file}
+    module := s.L.NewTable()
+    s.L.SetFuncs(module, map[string]lua.LGFunction{
+        "add_button": s.addButton,
+        "write":      s.write,
+        "run":        s.run,
+    })
+    s.L.SetGlobal("cmdtui", module)
+    return s
+}
+
+func (s *scripting) close() {
+    s.L.Close()
+}
+
+// call runs the global hook name if the config defined one.
+func (s *scripting) call(name string, args ...lua.LValue) error {
+    fn, ok := s.L.GetGlobal(name).(*lua.LFunction)
+    if !ok {
+        return nil
+    }
+    return s.L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, args...)
+}
+
+// transform calls a button's transform function with the command's output
+// and returns the string it produced.
+func (s *scripting) transform(fn *lua.LFunction, output string) (string, error) {
+    if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, lua.LString(output)); err != nil {
+        return "", err
+    }
+    result := s.L.Get(-1)
+    s.L.Pop(1)
+    if result == lua.LNil {
+        return "", nil
+    }
+    return result.String(), nil
+}
+
+// validate calls a prompt's validate function with the typed argument. The
+// function returns true or nothing for good arguments, and false or a
+// message explaining the problem otherwise.
+func (s *scripting) validate(fn *lua.LFunction, value string) (string, error) {
+    if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, lua.LString(value)); err != nil {
+        return "", err
+    }
+    result := s.L.Get(-1)
+    s.L.Pop(1)
+    switch result {
+    case lua.LNil, lua.LTrue:
+        return "", nil
+    case lua.LFalse:
+        return "invalid argument", nil
+    }
+    return result.String(), nil
+}
+
+// complete calls a completions function with the typed prefix and returns
+// the strings in the list it produced.
+func (s *scripting) complete(fn *lua.LFunction, prefix string) ([]string, error) {
+    if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, lua.LString(prefix)); err != nil {
+        return nil, err
+    }
+    result := s.L.Get(-1)
+    s.L.Pop(1)
+    t, ok := result.(*lua.LTable)
+    if !ok {
+        if result == lua.LNil {
+            return nil, nil
+        }
+        return nil, fmt.Errorf("expected a list of suggestions, got %s", result.Type())
+    }
+    var suggestions []string
+    t.ForEach(func(_, v lua.LValue) {
+        suggestions = append(suggestions, v.String())
+    })
+    return suggestions, nil
+}
+
+// enabled calls a button's enabled function and reports whether it returned
+// a true value.
+func (s *scripting) enabled(fn *lua.LFunction) (bool, error) {
+    if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}); err != nil {
+        return false, err
+    }
+    result := s.L.Get(-1)
+    s.L.Pop(1)
+    return lua.LVAsBool(result), nil
+}
+
+// generate calls a generator's button function with a line of output and
+// returns the button table it produced, or nil to skip the line.
+func (s *scripting) generate(fn *lua.LFunction, line string) (lua.LValue, error) {
+    if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, lua.LString(line)); err != nil {
+        return lua.LNil, err
+    }
+    result := s.L.Get(-1)
+    s.L.Pop(1)
+    if _, ok := result.(*lua.LTable); !ok && result != lua.LNil {
+        return lua.LNil, fmt.Errorf("expected a button table or nil, got %s", result.Type())
+    }
+    return result, nil
+}
+
+// commandValue converts cmd into the table handed to hooks.
+func (s *scripting) commandValue(cmd command) lua.LValue {
+    argv := s.L.NewTable()
+    for _, arg := range cmd.cmd {
+        argv.Append(lua.LString(arg))
+    }
+    t := s.L.NewTable()
+    t.RawSetString("name", lua.LString(cmd.name))
+    t.RawSetString("cmd", argv)
+    return t
+}
+
+func (s *scripting) addButton(L *lua.LState) int {
+    r := newConfigReader(s.file)
+    cmd, ok := extractCommand(configNode{r: r, path: []pathSegment{{key: "cmdtui.add_button"}}, value: luaToGo(L.CheckTable(1))})
+    if err := r.err(); err != nil || !ok {
+        L.RaiseError("%v", err)
+        return 0
+    }
+    s.pending = append(s.pending, func(m *model) tea.Cmd {
+        m.sharedCommands = append(m.sharedCommands, cmd)
+        return m.useProfile(m.profile)
+    })
+    return 0
+}
+
+func (s *scripting) write(L *lua.LState) int {
+    target := L.CheckAny(1)
+    text := L.CheckString(2)
+    if !strings.HasSuffix(text, "\n") {
+        text += "\n"
+    }
+    s.pending = append(s.pending, func(m *model) tea.Cmd {
+        tab, err := m.findTab(target)
+        if err != nil {
+            m.appendOutput(m.currentTab, fmt.Sprintf("Error in cmdtui.write: %v\n", err))
+            return nil
+        }
+        m.appendOutput(tab, text)
+        return nil
+    })
+    return 0
+}
+
+func (s *scripting) run(L *lua.LState) int {
+    name := L.CheckString(1)
+    s.pending = append(s.pending, func(m *model) tea.Cmd {
+        if cmd, ok := m.findButton(name); ok {
+            return m.runButton(cmd)
+        }
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error in cmdtui.run: no button named %q\n", name))
+        return nil
+    })
+    return 0
+}
+
+// enabledCommands returns the commands whose enabled function, if they have
+// one, returns true. Buttons whose function fails stay listed, and the error
+// is written to the current tab the first time it happens.
+func (m *model) enabledCommands(commands []command) []command {
+    if m.script == nil {
+        return commands
+    }
+    var enabled []command
+    for _, cmd := range commands {
+        if cmd.enabled != nil {
+            ok, err := m.script.enabled(cmd.enabled)
+            if err != nil {
+                if m.enabledErrors[cmd.name] != err.Error() {
+                    m.appendOutput(m.currentTab, fmt.Sprintf("Error in enabled of %s: %v\n", cmd.name, err))
+                }
+                m.enabledErrors[cmd.name] = err.Error()
+                ok = true
+            } else {
+                delete(m.enabledErrors, cmd.name)
+            }
+            if !ok {
+                continue
+            }
+        }
+        enabled = append(enabled, cmd)
+    }
+    return enabled
+}
+
+// findTab resolves a tab given from Lua either as a 1-based number or a title.
+func (m *model) findTab(target lua.LValue) (int, error) {
+    switch t := target.(type) {
+    case lua.LNumber:
+        if i := int(t) - 1; i >= 0 && i < len(m.tabs) {
+            return i, nil
+        }
+    case lua.LString:
+        for i, tab := range m.tabs {
+            if tab.title == string(t) {
+                return i, nil
+            }
+        }
+    }
+    return 0, fmt.Errorf("no tab %s", target.String())
+}
+
+// runHook calls a Lua hook and applies whatever it queued through the
+// cmdtui table. Errors raised by the hook are written to the current tab.
+func (m *model) runHook(name string, args ...lua.LValue) tea.Cmd {
+    if m.script == nil {
+        return nil
+    }
+    if err := m.script.call(name, args...); err != nil {
+        m.appendOutput(m.currentTab, fmt.Sprintf("Error in %s: %v\n", name, err))
+    }
+    return m.applyScriptActions()
+}
+
+// applyScriptActions applies the actions the script has queued so far.
+func (m *model) applyScriptActions() tea.Cmd {
+    if m.script == nil {
+        return nil
+    }
+    actions := m.script.pending
+    m.script.pending = nil
+
+    var cmds []tea.Cmd
+    for _, action := range actions {
+        cmds = append(cmds, action(m))
+    }
+    return tea.Batch(cmds...)
+}
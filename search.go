@@ -0,0 +1,124 @@
+package main
+
+import (
+    "fmt"
+    "regexp"
+    "strings"
+)
+
+// Matches are shown in reverse video, switched off again with the code that
+// leaves the output's own colors alone, which a full reset would not.
+const (
+    matchStart = "\x1b[7m"
+    matchEnd   = "\x1b[27m"
+)
+
+// startSearch asks for the text to highlight in the current tab's output.
+func (m *model) startSearch() {
+    m.searching = true
+    m.input.SetValue(m.tabs[m.currentTab].search)
+    m.input.CursorEnd()
+    m.input.Placeholder = "Search the output, empty to stop searching..."
+    m.input.Focus()
+    m.focus = focusInput
+    m.suggestions = nil
+}
+
+// search highlights every match of text in the current tab and scrolls to
+// the first one. An empty text ends the search.
+func (m *model) search(text string) {
+    t := &m.tabs[m.currentTab]
+    t.search = text
+    t.pattern = nil
+    if text != "" {
+        // Compiled once here, highlightMatches runs for every line shown
+        t.pattern = regexp.MustCompile("(?i)" + regexp.QuoteMeta(text))
+    }
+    t.showOutput()
+    if len(t.matchLines) > 0 {
+        t.jumpTo(t.matchLines[0])
+        m.focus = focusViewport
+    }
+}
+
+// nextMatch scrolls the current tab to the next line with a match after the
+// current line, or the one before it when dir is negative, wrapping around
+// at the ends.
+func (m *model) nextMatch(dir int) {
+    t := &m.tabs[m.currentTab]
+    if len(t.matchLines) == 0 {
+        return
+    }
+    from := t.currentLine()
+    target := t.matchLines[0]
+    if dir < 0 {
+        target = t.matchLines[len(t.matchLines)-1]
+    }
+    if dir > 0 {
+        for _, line := range t.matchLines {
+            if line > from {
+                target = line
+                break
+            }
+        }
+    } else {
+        for i := len(t.matchLines) - 1; i >= 0; i-- {
+            if t.matchLines[i] < from {
+                target = t.matchLines[i]
+                break
+            }
+        }
+    }
+    t.jumpTo(target)
+}
+
+// searchStatus describes the current tab's search for the status line, or
+// returns "" when it has none.
+func (m *model) searchStatus() string {
+    t := m.tabs[m.currentTab]
+    if t.search == "" {
+        return ""
+    }
+    return fmt.Sprintf("Search %q: %d matches", t.search, t.matches)
+}
+
+// highlightMatches marks every match of pattern in output, looking past
+// the colors in it, and returns how many there were. A nil pattern matches
+// nothing.
+func highlightMatches(output string, pattern *regexp.Regexp) (string, int) {
+    if pattern == nil {
+        return output, 0
+    }
+
+    // Find the matches in the visible text, keeping where each of its bytes
+    // is in output to put the markers around them there
+    var plain strings.Builder
+    var positions []int
+    last := 0
+    addPlain := func(from, to int) {
+        plain.WriteString(output[from:to])
+        for i := from; i < to; i++ {
+            positions = append(positions, i)
+        }
+    }
+    for _, escape := range ansiEscape.FindAllStringIndex(output, -1) {
+        addPlain(last, escape[0])
+        last = escape[1]
+    }
+    addPlain(last, len(output))
+
+    matches := pattern.FindAllStringIndex(plain.String(), -1)
+    if len(matches) == 0 {
+        return output, 0
+    }
+    var b strings.Builder
+    last = 0
+    for _, match := range matches {
+        start, end := positions[match[0]], positions[match[1]-1]+1
+        b.WriteString(output[last:start])
+        b.WriteString(matchStart + output[start:end] + matchEnd)
+        last = end
+    }
+    b.WriteString(output[last:])
+    return b.String(), len(matches)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+    "errors"
+    "strings"
+)
+
+// shellWords splits a typed command into arguments the way a shell would,
+// without expanding anything: words are separated by unquoted whitespace,
+// single quotes keep everything up to the next one as is, double quotes
+// keep spaces but let a backslash escape ", \, $ and `, and outside quotes a
+// backslash escapes the character after it.
+func shellWords(line string) ([]string, error) {
+    var words []string
+    var word strings.Builder
+    inWord := false
+    runes := []rune(line)
+    for i := 0; i < len(runes); i++ {
+        r := runes[i]
+        switch {
+        case r == ' ' || r == '\t' || r == '\n':
+            if inWord {
+                words = append(words, word.String())
+                word.Reset()
+                inWord = false
+            }
+        case r == '\\':
+            inWord = true
+            if i+1 < len(runes) {
+                i++
+                word.WriteRune(runes[i])
+            }
+        case r == '\'':
+            inWord = true
+            end := indexRune(runes, i+1, '\'')
+            if end < 0 {
+                return nil, errors.New("unterminated single quote")
+            }
+            word.WriteString(string(runes[i+1 : end]))
+            i = end
+        case r == '"':
+            inWord = true
+            i++
+            for ; i < len(runes) && runes[i] != '"'; i++ {
+                if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+                    i++
+                }
+                word.WriteRune(runes[i])
+            }
+            if i == len(runes) {
+                return nil, errors.New("unterminated double quote")
+            }
+        default:
+            inWord = true
+            word.WriteRune(r)
+        }
+    }
+    if inWord {
+        words = append(words, word.String())
+    }
+    return words, nil
+}
+
+// indexRune returns the index of the first r in runes from start on, or -1.
+func indexRune(runes []rune, start int, r rune) int {
+    for i := start; i < len(runes); i++ {
+        if runes[i] == r {
+            return i
+        }
+    }
+    return -1
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+    "fmt"
+    "syscall"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// jobSignal is a signal offered by the signal menu.
+type jobSignal struct {
+    name   string
+    signal syscall.Signal
+    what   string
+}
+
+// signalMenu asks which signal to send to a running job, the selected
+// button's if it is running, otherwise the latest one started in the
+// current tab, and sends it to the job's processes.
+func (m *model) signalMenu() tea.Cmd {
+    j := m.signalTarget()
+    if j == nil {
+        m.appendOutput(m.currentTab, "No running job to signal\n")
+        return nil
+    }
+
+    candidates := make([]string, len(jobSignals))
+    for i, s := range jobSignals {
+        candidates[i] = fmt.Sprintf("%s  %s", s.name, s.what)
+    }
+    p := placeholder{name: "signal", what: "signals", candidates: func(command, map[string]string) ([]string, error) {
+        return candidates, nil
+    }}
+    cmd := command{name: "signal for " + j.cmd.name, cmd: []string{"{signal}"}}
+    id := j.id
+    picker := &pickerCommand{cmd: cmd, placeholder: p}
+    return tea.Exec(picker, func(err error) tea.Msg {
+        return placeholderPickedMsg{cmd: cmd, placeholder: p, value: picker.value, err: err, then: func(m *model, cmd command) tea.Cmd {
+            m.sendSignal(id, cmd.cmd[0])
+            return nil
+        }}
+    })
+}
+
+// signalTarget returns the job the signal menu acts on, or nil when
+// nothing is running.
+func (m *model) signalTarget() *job {
+    if idx, ok := m.selectedIndex(); ok && m.focus == focusList {
+        for i := len(m.jobs) - 1; i >= 0; i-- {
+            if m.jobs[i].cmd.name == m.commands[idx].name {
+                return m.jobs[i]
+            }
+        }
+    }
+    for i := len(m.jobs) - 1; i >= 0; i-- {
+        if m.jobs[i].tab == m.currentTab {
+            return m.jobs[i]
+        }
+    }
+    if len(m.jobs) > 0 {
+        return m.jobs[len(m.jobs)-1]
+    }
+    return nil
+}
+
+// sendSignal sends the signal called name to the job with the given ID.
+func (m *model) sendSignal(id int, name string) {
+    j := m.job(id)
+    if j == nil {
+        m.appendOutput(m.currentTab, "The job finished before the signal was sent\n")
+        return
+    }
+    for _, s := range jobSignals {
+        if s.name != name {
+            continue
+        }
+        for _, c := range j.procs {
+            if err := signalProcess(c, s.signal); err != nil {
+                m.appendOutput(j.tab, fmt.Sprintf("Error sending SIG%s to %s: %v\n", name, j.cmd.name, err))
+                return
+            }
+        }
+        m.appendOutput(j.tab, fmt.Sprintf("Sent SIG%s to %s\n", name, j.cmd.name))
+        return
+    }
+}
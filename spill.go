@@ -0,0 +1,79 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strings"
+)
+
+// defaultTabMemory is how many megabytes of output each tab keeps in memory
+// unless the config's tab_memory_mb says otherwise.
+const defaultTabMemory = 64
+
+// spill is the older output of a tab moved to a temporary file once the tab
+// held more than its limit. The pager still shows it.
+type spill struct {
+    f     *os.File
+    lines int
+    err   error // Creating or writing the file failed, lines since are dropped
+}
+
+// spillOutput moves the oldest lines of the tab's output to its spill file,
+// down to three quarters of limit bytes so it isn't done again for every
+// line that follows. The output needs redrawing afterwards.
+func (t *outputTab) spillOutput(limit int) {
+    if t.spill == nil {
+        t.spill = &spill{}
+        t.spill.f, t.spill.err = os.CreateTemp("", "cmdtui-tab-*.log")
+    }
+    n := 0
+    for t.size > limit*3/4 && n < len(t.lines)-1 {
+        t.size -= len(t.lines[n]) + 1
+        n++
+    }
+    if t.spill.err == nil {
+        _, t.spill.err = io.WriteString(t.spill.f, strings.Join(t.lines[:n], "\n")+"\n")
+    }
+    // Copied so the moved lines can be freed
+    t.lines = append([]string(nil), t.lines[n:]...)
+    t.spill.lines += n
+}
+
+// spillNotice is shown above the output of a tab that moved some of it to
+// disk.
+func (t *outputTab) spillNotice() string {
+    if t.spill.err != nil {
+        return statusStyle.Render(fmt.Sprintf("▲ %d earlier lines were dropped, saving them failed: %v", t.spill.lines, t.spill.err))
+    }
+    return statusStyle.Render(fmt.Sprintf("▲ %d earlier lines are in %s, the pager shows them with the rest",
+        t.spill.lines, t.spill.f.Name()))
+}
+
+// allOutput returns the tab's whole output, with what was moved to disk.
+func (t *outputTab) allOutput() (string, error) {
+    if t.spill == nil || t.spill.f == nil {
+        return t.text(), nil
+    }
+    older, err := os.ReadFile(t.spill.f.Name())
+    if err != nil {
+        return "", err
+    }
+    return string(older) + t.text(), nil
+}
+
+// dropSpill deletes the tab's spill file.
+func (t *outputTab) dropSpill() {
+    if t.spill != nil && t.spill.f != nil {
+        t.spill.f.Close()
+        os.Remove(t.spill.f.Name())
+    }
+    t.spill = nil
+}
+
+// dropSpills deletes the spill files of every tab, when cmdtui exits.
+func (m *model) dropSpills() {
+    for i := range m.tabs {
+        m.tabs[i].dropSpill()
+    }
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+    "github.com/charmbracelet/lipgloss"
+)
+
+// splitMode is how the output area is divided between two tabs.
+type splitMode int
+
+const (
+    splitOff splitMode = iota
+    splitSideBySide
+    splitStacked
+)
+
+// toggleSplit cycles between a single output pane, two panes side by side
+// and two stacked panes. The second pane starts out showing the tab after
+// the current one. Comparing ends with the side by side split.
+func (m *model) toggleSplit() {
+    if len(m.tabs) < 2 {
+        return
+    }
+    m.split = (m.split + 1) % 3
+    m.compare = m.compare && m.split == splitSideBySide
+    if m.split == splitSideBySide {
+        m.splitTabs = [2]int{m.currentTab, (m.currentTab + 1) % len(m.tabs)}
+        m.splitPane = 0
+    }
+    m.resizeTabs()
+}
+
+// switchPane moves focus to the other output pane of a split.
+func (m *model) switchPane() {
+    if m.split == splitOff {
+        return
+    }
+    m.splitPane = 1 - m.splitPane
+    m.currentTab = m.splitTabs[m.splitPane]
+    m.focus = focusViewport
+}
+
+// selectTab makes tab i the current one. In a split it replaces the tab in
+// the focused pane, swapping panes if the other one already shows it.
+func (m *model) selectTab(i int) {
+    m.currentTab = i
+    if m.split == splitOff {
+        return
+    }
+    if other := 1 - m.splitPane; m.splitTabs[other] == i {
+        m.splitTabs[other] = m.splitTabs[m.splitPane]
+    }
+    m.splitTabs[m.splitPane] = i
+    m.resizeTabs()
+}
+
+// layoutTab is the tab whose layout arranges the screen, the first pane's
+// while split.
+func (m *model) layoutTab() int {
+    if m.split != splitOff {
+        return m.splitTabs[0]
+    }
+    return m.currentTab
+}
+
+// resizeSplit shares the output area of the layout between the two panes.
+// Each pane has its own border and padding, which come out of the shared
+// space.
+func (m *model) resizeSplit() {
+    if m.split == splitOff {
+        return
+    }
+    if len(m.tabs) < 2 || m.splitTabs[0] >= len(m.tabs) || m.splitTabs[1] >= len(m.tabs) {
+        // The config no longer has the tabs that were split
+        m.split = splitOff
+        return
+    }
+
+    layout := m.layoutOf(m.splitTabs[0])
+    width, height := m.outputSize(layout)
+    first, second := &m.tabs[m.splitTabs[0]].viewport, &m.tabs[m.splitTabs[1]].viewport
+    first.Width, second.Width = width, width
+    first.Height, second.Height = height, height
+    frameWidth, frameHeight := paneStyle(focusViewport, false).GetFrameSize()
+    if m.split == splitSideBySide {
+        first.Width = (width - frameWidth) / 2
+        second.Width = width - frameWidth - first.Width
+    } else {
+        first.Height = (height - frameHeight) / 2
+        second.Height = height - frameHeight - first.Height
+    }
+}
+
+// splitView renders both panes of a split, the focused one with style and
+// the other with other.
+func (m *model) splitView(style, other func(tab int) lipgloss.Style) string {
+    panes := make([]string, 2)
+    for i, tab := range m.splitTabs {
+        s := other(tab)
+        if i == m.splitPane {
+            s = style(tab)
+        }
+        panes[i] = s.Render(m.tabs[tab].viewport.View())
+    }
+    if m.split == splitSideBySide {
+        return lipgloss.JoinHorizontal(lipgloss.Top, panes...)
+    }
+    return lipgloss.JoinVertical(lipgloss.Left, panes...)
+}
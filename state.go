@@ -0,0 +1,229 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "io/fs"
+    "math"
+    "os"
+    "path/filepath"
+    "sort"
+    "time"
+
+    "github.com/charmbracelet/bubbles/list"
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// appState is what cmdtui remembers between sessions. It is kept as JSON in
+// the state file, see statePath.
+type appState struct {
+    Usage  map[string]buttonUsage `json:"usage"`            // By button name
+    SortBy string                 `json:"sort,omitempty"`   // One of listSorts, "" for config order
+    Pinned []string               `json:"pinned,omitempty"` // Names of the buttons kept at the top of the list
+}
+
+// buttonUsage counts the times a button was run from the TUI.
+type buttonUsage struct {
+    Count int       `json:"count"`
+    Last  time.Time `json:"last"`
+}
+
+// listSorts are the orders the button list cycles through, after the order
+// of the config.
+var listSorts = []string{"recent"}
+
+// usageHalfLife is how long it takes for a run to count half as much when
+// sorting by recent use, so buttons used a lot last month sink below the
+// ones used a few times today.
+const usageHalfLife = 7 * 24 * time.Hour
+
+// statePath returns where the state is kept: cmdtui/state.json in
+// $XDG_STATE_HOME, or ~/.local/state.
+func statePath() (string, error) {
+    dir := os.Getenv("XDG_STATE_HOME")
+    if dir == "" {
+        home, err := os.UserHomeDir()
+        if err != nil {
+            return "", err
+        }
+        dir = filepath.Join(home, ".local", "state")
+    }
+    return filepath.Join(dir, "cmdtui", "state.json"), nil
+}
+
+// loadState reads the state file. A missing file is an empty state.
+func loadState() (appState, error) {
+    state := appState{Usage: make(map[string]buttonUsage)}
+    path, err := statePath()
+    if err != nil {
+        return state, err
+    }
+    data, err := os.ReadFile(path)
+    if errors.Is(err, fs.ErrNotExist) {
+        return state, nil
+    } else if err != nil {
+        return state, err
+    }
+    if err := json.Unmarshal(data, &state); err != nil {
+        return state, err
+    }
+    if state.Usage == nil {
+        state.Usage = make(map[string]buttonUsage)
+    }
+    return state, nil
+}
+
+// save writes the state file, replacing it in one step so a crash can't
+// leave it half written.
+func (s appState) save() error {
+    path, err := statePath()
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return err
+    }
+    data, err := json.MarshalIndent(s, "", "  ")
+    if err != nil {
+        return err
+    }
+    tmp := path + ".tmp"
+    if err := os.WriteFile(tmp, data, 0o644); err != nil {
+        return err
+    }
+    return os.Rename(tmp, path)
+}
+
+// score rates how often and how recently a button was used, each run
+// counting less the longer ago the button was last run.
+func (u buttonUsage) score(now time.Time) float64 {
+    if u.Count == 0 {
+        return 0
+    }
+    return float64(u.Count) * math.Pow(0.5, float64(now.Sub(u.Last))/float64(usageHalfLife))
+}
+
+// noteUse records a run of the button called name and saves the state.
+func (m *model) noteUse(name string) {
+    u := m.state.Usage[name]
+    u.Count++
+    u.Last = time.Now()
+    m.state.Usage[name] = u
+    m.saveState()
+}
+
+// saveState writes the state file, reporting failures in the current tab.
+func (m *model) saveState() {
+    if err := m.state.save(); err != nil {
+        m.appendOutput(m.currentTab, "Error saving state: "+err.Error()+"\n")
+    }
+}
+
+// nextSort switches the button list to the next order in listSorts, or back
+// to the order of the config.
+func (m *model) nextSort() tea.Cmd {
+    sorts := append([]string{""}, listSorts...)
+    next := ""
+    for i, s := range sorts {
+        if s == m.state.SortBy {
+            next = sorts[(i+1)%len(sorts)]
+        }
+    }
+    m.state.SortBy = next
+    m.saveState()
+    if next == "" {
+        next = "config order"
+    }
+    m.appendOutput(m.currentTab, "Sorting buttons by "+next+"\n")
+    return m.sortCommands()
+}
+
+// isPinned reports whether the button called name is pinned.
+func (s appState) isPinned(name string) bool {
+    for _, pinned := range s.Pinned {
+        if pinned == name {
+            return true
+        }
+    }
+    return false
+}
+
+// togglePin pins the selected button to the top of the list, or unpins it.
+func (m *model) togglePin() tea.Cmd {
+    idx, ok := m.selectedIndex()
+    if !ok {
+        return nil
+    }
+    name := m.commands[idx].name
+    if m.state.isPinned(name) {
+        var pinned []string
+        for _, p := range m.state.Pinned {
+            if p != name {
+                pinned = append(pinned, p)
+            }
+        }
+        m.state.Pinned = pinned
+        m.appendOutput(m.currentTab, "Unpinned "+name+"\n")
+    } else {
+        m.state.Pinned = append(m.state.Pinned, name)
+        m.appendOutput(m.currentTab, "Pinned "+name+"\n")
+    }
+    m.saveState()
+    return m.sortCommands()
+}
+
+// sortCommands puts m.commands in the order chosen with nextSort, pinned
+// buttons first, and refreshes the list, keeping the same button selected.
+// The order is left alone while a prompt refers to a button by position.
+func (m *model) sortCommands() tea.Cmd {
+    if m.prompInput || m.confirming {
+        return nil
+    }
+    selected := ""
+    if idx, ok := m.selectedIndex(); ok {
+        selected = m.commands[idx].name
+    }
+
+    m.commands = m.enabledCommands(m.configOrder())
+    if m.state.SortBy == "recent" {
+        now := time.Now()
+        sort.SliceStable(m.commands, func(i, j int) bool {
+            return m.state.Usage[m.commands[i].name].score(now) > m.state.Usage[m.commands[j].name].score(now)
+        })
+    }
+    sort.SliceStable(m.commands, func(i, j int) bool {
+        return m.state.isPinned(m.commands[i].name) && !m.state.isPinned(m.commands[j].name)
+    })
+
+    cmd := m.numberFilters(m.list.SetItems(commandItems(m.commands, m.state, m.tag)))
+    if m.list.FilterState() != list.Unfiltered {
+        // Positions are in the filtered items, which are only known once
+        // the filter has run over the new ones
+        return cmd
+    }
+    m.list.Select(0)
+    for i, item := range m.list.Items() {
+        if m.commands[item.(listItem).index].name == selected {
+            m.list.Select(i)
+            break
+        }
+    }
+    return cmd
+}
+
+// configOrder returns the buttons of the active profile in the order of
+// the config: the shared ones, those made by generators and the profile's.
+func (m *model) configOrder() []command {
+    commands := append([]command(nil), m.sharedCommands...)
+    for _, g := range m.generators {
+        if !g.loaded {
+            commands = append(commands, g.standIn())
+            continue
+        }
+        commands = append(commands, g.commands...)
+    }
+    if m.profile >= 0 {
+        commands = append(commands, m.profiles[m.profile].commands...)
+    }
+    return commands
+}
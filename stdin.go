@@ -0,0 +1,94 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "os/exec"
+    "strings"
+)
+
+// stdinSpec is what a button gives its command to read on standard input,
+// such as SQL for psql or a manifest for kubectl apply -f -: text written
+// in the config, or a file read when the button runs.
+type stdinSpec struct {
+    text string
+    file string // Relative to the button's cwd
+}
+
+// extractStdin reads a button's stdin, the text itself or a table naming
+// the file, like { file = "deploy.yaml" }.
+func extractStdin(node configNode) stdinSpec {
+    if _, ok := node.value.(map[string]interface{}); ok {
+        return stdinSpec{file: node.key("file").requiredStr()}
+    }
+    return stdinSpec{text: node.str("")}
+}
+
+func (s stdinSpec) isSet() bool {
+    return s.text != "" || s.file != ""
+}
+
+// feed makes s the standard input of c. The returned function closes the
+// file once c has started, or finished for commands started elsewhere.
+func (s stdinSpec) feed(c *exec.Cmd) (func(), error) {
+    switch {
+    case s.file != "":
+        f, err := os.Open(s.file)
+        if err != nil {
+            return func() {}, err
+        }
+        c.Stdin = f
+        return func() { f.Close() }, nil
+    case s.text != "":
+        c.Stdin = strings.NewReader(s.text)
+    }
+    return func() {}, nil
+}
+
+// inputJob returns the job lines typed in tab are sent to: the newest one
+// on a pseudo-terminal, or in stdin mode the newest one still reading its
+// standard input. It returns nil when typed lines run commands.
+func (m *model) inputJob(tab int) *job {
+    if j := m.terminalJob(tab); j != nil {
+        return j
+    }
+    if !m.stdinMode {
+        return nil
+    }
+    for i := len(m.jobs) - 1; i >= 0; i-- {
+        if j := m.jobs[i]; j.tab == tab && j.stdin != nil {
+            return j
+        }
+    }
+    return nil
+}
+
+// toggleStdin switches stdin mode, where the input box types into the
+// current tab's running command rather than starting new ones.
+func (m *model) toggleStdin() {
+    if m.stdinMode {
+        m.stdinMode = false
+        return
+    }
+    m.stdinMode = true
+    if m.inputJob(m.currentTab) == nil {
+        m.stdinMode = false
+        m.appendOutput(m.currentTab, "No running command in this tab reads input, buttons need input = true\n")
+        return
+    }
+    m.input.Focus()
+    m.focus = focusInput
+}
+
+// closeStdin ends the input of the job typed lines go to, leaving stdin
+// mode.
+func (m *model) closeStdin() {
+    j := m.inputJob(m.currentTab)
+    m.stdinMode = false
+    if j == nil || j.pty != nil {
+        return
+    }
+    if err := j.closeInput(); err != nil {
+        m.appendOutput(j.tab, fmt.Sprintf("Error closing the input of %s: %v\n", j.cmd.name, err))
+    }
+}
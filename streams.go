@@ -0,0 +1,84 @@
+package main
+
+import "github.com/charmbracelet/lipgloss"
+
+// stderrStyle sets lines from standard error apart in a tab's whole output.
+var stderrStyle = lipgloss.NewStyle().Foreground(failureColor)
+
+// streamView picks which output of a tab's last run the tab shows.
+type streamView int
+
+const (
+    streamsCombined streamView = iota // The whole output of the tab
+    streamsStdout
+    streamsStderr
+)
+
+// label names the view in the tab's title, "" for the combined one.
+func (v streamView) label() string {
+    switch v {
+    case streamsStdout:
+        return "stdout"
+    case streamsStderr:
+        return "stderr"
+    }
+    return ""
+}
+
+// record keeps a line of output of j with the stream it came from.
+func (j *job) record(line string, stderr bool) {
+    j.captured.WriteString(line + "\n")
+    if stderr {
+        j.stderr.WriteString(line + "\n")
+    } else {
+        j.stdout.WriteString(line + "\n")
+    }
+}
+
+// outputLine returns a line of output as the tab shows it, in red when it
+// came from standard error so errors stand out among verbose output.
+func outputLine(line string, stderr bool) string {
+    if stderr && line != "" {
+        return stderrStyle.Render(line)
+    }
+    return line
+}
+
+// streamOutput returns the output of the last run in the tab for the view,
+// and false when the tab shows its whole output.
+func (t *outputTab) streamOutput() (string, bool) {
+    if t.streams == streamsCombined || t.run == nil {
+        return "", false
+    }
+    var out string
+    if t.streams == streamsStdout {
+        out = t.run.stdout.String()
+    } else {
+        out = t.run.stderr.String()
+    }
+    if out == "" {
+        out = "Nothing on " + t.streams.label() + " in the last run of " + t.run.cmd.name
+    }
+    return out, true
+}
+
+// toggleStreams switches the current tab between showing its whole output,
+// only the standard output of its last run and only the standard error.
+func (m *model) toggleStreams() {
+    t := &m.tabs[m.currentTab]
+    if t.run == nil {
+        t.streams = streamsCombined
+        return
+    }
+    t.streams = (t.streams + 1) % 3
+    t.showOutput()
+    t.viewport.GotoBottom()
+}
+
+// streamsTitle returns the tab's title, marked when it shows one stream.
+func (t *outputTab) streamsTitle() string {
+    if label := t.streams.label(); label != "" && t.run != nil {
+        return t.title + " (" + label + ")"
+    }
+    return t.title
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+    "regexp"
+
+    "github.com/charmbracelet/lipgloss"
+)
+
+// styleSetter changes one property of a style, as set in the config's
+// styles section.
+type styleSetter func(lipgloss.Style) lipgloss.Style
+
+// styles returns pointers to every style of the UI, keyed by the name used
+// to change it in the config's styles section.
+func styles() map[string]*lipgloss.Style {
+    return map[string]*lipgloss.Style{
+        "doc":               &docStyle,
+        "border":            &normalBorder,
+        "focused_border":    &focusedBorder,
+        "active_button":     &activeButton,
+        "inactive_button":   &inactiveButton,
+        "elevated_button":   &elevatedButton,
+        "elevated":          &elevatedStyle,
+        "elevated_banner":   &elevatedBanner,
+        "tab_border":        &tabBorder,
+        "active_tab_border": &activeTabBorder,
+        "tab":               &tab,
+        "active_tab":        &activeTab,
+        "tab_gap":           &tabGap,
+        "spinner":           &spinnerStyle,
+        "status":            &statusStyle,
+        "error":             &errorStyle,
+        "stderr":            &stderrStyle,
+        "pinned_divider":    &pinnedDivider,
+        "cheat_sheet":       &cheatSheetBox,
+        "editor":            &editorBox,
+        "run_header":        &runHeaderStyle,
+        "run_current":       &runCurrent,
+        "run_success":       &runSuccess,
+        "run_failure":       &runFailure,
+        "diff_added":        &diffAdded,
+        "diff_removed":      &diffRemoved,
+        "diff_hunk":         &diffHunk,
+        "table_header":      &tableHeaderStyle,
+        "table_cell":        &tableCellStyle,
+        "markdown_heading":  &markdownHeading,
+        "markdown_code":     &markdownCode,
+        "markdown_quote":    &markdownQuote,
+        "markdown_bold":     &markdownBold,
+        "markdown_italic":   &markdownItalic,
+        "list_title":        &listTitle,
+        "help_key":          &helpKey,
+        "help_description":  &helpDescription,
+    }
+}
+
+// defaultStyles are the styles as built in, which the config's changes are
+// made to, again on every reload.
+var defaultStyles = func() map[string]lipgloss.Style {
+    defaults := make(map[string]lipgloss.Style)
+    for name, style := range styles() {
+        defaults[name] = *style
+    }
+    return defaults
+}()
+
+// paneBorders are the borders the config's borders section chose for the
+// list, output and input panes. Panes not in it keep the border of the
+// border and focused_border styles.
+var paneBorders map[focusState]lipgloss.Border
+
+// applyStyles resets every style and makes the theme's changes to them,
+// then the config's.
+func applyStyles(t theme, overrides map[string][]styleSetter, borders map[focusState]lipgloss.Border) {
+    changes := t.styleChanges()
+    for name, style := range styles() {
+        *style = defaultStyles[name]
+        for _, set := range append(changes[name], overrides[name]...) {
+            *style = set(*style)
+        }
+    }
+    paneBorders = borders
+}
+
+// restyle hands the styles to the parts of m that keep their own, after
+// applyStyles.
+func (m *model) restyle(t theme) {
+    m.spinner.Style = spinnerStyle
+    m.list.Styles.Title = listTitle
+    m.help.Styles.ShortKey, m.help.Styles.FullKey = helpKey, helpKey
+    m.help.Styles.ShortDesc, m.help.Styles.FullDesc = helpDescription, helpDescription
+    m.progress = t.progressBar()
+}
+
+// paneStyle returns the style of the pane for f, focused or not, with its
+// border.
+func paneStyle(f focusState, focused bool) lipgloss.Style {
+    style := normalBorder
+    if focused {
+        style = focusedBorder
+    }
+    if border, ok := paneBorders[f]; ok {
+        style = withBorder(style, border)
+    }
+    return style
+}
+
+// borderTypes are the borders a style or pane can have. none leaves the
+// border out, along with the space it takes.
+var borderTypes = map[string]lipgloss.Border{
+    "normal":  lipgloss.NormalBorder(),
+    "rounded": lipgloss.RoundedBorder(),
+    "double":  lipgloss.DoubleBorder(),
+    "thick":   lipgloss.ThickBorder(),
+    "hidden":  lipgloss.HiddenBorder(),
+    "none":    {},
+}
+
+// withBorder returns s drawn with border, on the sides s has a border on,
+// or all of them when it has none.
+func withBorder(s lipgloss.Style, border lipgloss.Border) lipgloss.Style {
+    switch {
+    case border == lipgloss.Border{}:
+        return s.Border(border, false)
+    case s.GetBorderTop() || s.GetBorderRight() || s.GetBorderBottom() || s.GetBorderLeft():
+        return s.BorderStyle(border)
+    }
+    return s.Border(border)
+}
+
+// extractBorder reads the name of a border type.
+func extractBorder(n configNode) (lipgloss.Border, bool) {
+    border, ok := borderTypes[n.str("")]
+    if !ok {
+        n.fail("expected normal, rounded, double, thick, hidden or none, got %s", describe(n.value))
+    }
+    return border, ok
+}
+
+// paneNames are the panes the borders section can name.
+var paneNames = map[string]focusState{
+    "list":   focusList,
+    "output": focusViewport,
+    "input":  focusInput,
+}
+
+// extractBorders reads the optional borders section: the border of every
+// pane, or a table with the border of each pane named in it.
+func extractBorders(section configNode) map[focusState]lipgloss.Border {
+    borders := make(map[focusState]lipgloss.Border)
+    if _, ok := section.value.(string); ok {
+        if border, ok := extractBorder(section); ok {
+            for _, f := range paneNames {
+                borders[f] = border
+            }
+        }
+        return borders
+    }
+    for _, name := range section.keys() {
+        f, ok := paneNames[name]
+        if !ok {
+            section.key(name).fail("unknown pane, expected list, output or input")
+            continue
+        }
+        if border, ok := extractBorder(section.key(name)); ok {
+            borders[f] = border
+        }
+    }
+    return borders
+}
+
+// colorPattern matches what lipgloss takes for a color: a number from the
+// 256 color palette or a hex color.
+var colorPattern = regexp.MustCompile(`^(\d{1,3}|#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6})$`)
+
+// extractStyles reads the optional styles section, where each style name
+// maps to a table of the properties to change, such as
+// { foreground = "#ff8800", bold = true, padding = {0, 2} }.
+func extractStyles(section configNode) map[string][]styleSetter {
+    known := styles()
+    overrides := make(map[string][]styleSetter)
+    for _, name := range section.keys() {
+        node := section.key(name)
+        if _, ok := known[name]; !ok {
+            node.fail("unknown style")
+            continue
+        }
+        for _, property := range node.keys() {
+            if set := extractStyleProperty(node.key(property), property); set != nil {
+                overrides[name] = append(overrides[name], set)
+            }
+        }
+    }
+    return overrides
+}
+
+// extractStyleProperty reads one property of a style, returning nil when it
+// is invalid.
+func extractStyleProperty(n configNode, property string) styleSetter {
+    switch property {
+    case "foreground", "background", "border_foreground":
+        color, ok := extractColor(n)
+        if !ok {
+            return nil
+        }
+        switch property {
+        case "foreground":
+            return func(s lipgloss.Style) lipgloss.Style { return s.Foreground(color) }
+        case "background":
+            return func(s lipgloss.Style) lipgloss.Style { return s.Background(color) }
+        }
+        return func(s lipgloss.Style) lipgloss.Style { return s.BorderForeground(color) }
+    case "bold", "italic", "underline", "faint", "reverse":
+        on := n.boolean(false)
+        switch property {
+        case "bold":
+            return func(s lipgloss.Style) lipgloss.Style { return s.Bold(on) }
+        case "italic":
+            return func(s lipgloss.Style) lipgloss.Style { return s.Italic(on) }
+        case "underline":
+            return func(s lipgloss.Style) lipgloss.Style { return s.Underline(on) }
+        case "faint":
+            return func(s lipgloss.Style) lipgloss.Style { return s.Faint(on) }
+        }
+        return func(s lipgloss.Style) lipgloss.Style { return s.Reverse(on) }
+    case "padding", "margin":
+        sides, ok := extractSpacing(n)
+        if !ok {
+            return nil
+        }
+        if property == "padding" {
+            return func(s lipgloss.Style) lipgloss.Style { return s.Padding(sides...) }
+        }
+        return func(s lipgloss.Style) lipgloss.Style { return s.Margin(sides...) }
+    case "border":
+        border, ok := extractBorder(n)
+        if !ok {
+            return nil
+        }
+        return func(s lipgloss.Style) lipgloss.Style { return withBorder(s, border) }
+    }
+    n.fail("unknown style property")
+    return nil
+}
+
+// extractColor reads a color, either one for every terminal or a table with
+// one for light and one for dark backgrounds.
+func extractColor(n configNode) (lipgloss.TerminalColor, bool) {
+    if _, ok := n.value.(map[string]interface{}); ok {
+        light, lightOK := extractColorValue(n.key("light"))
+        dark, darkOK := extractColorValue(n.key("dark"))
+        return lipgloss.AdaptiveColor{Light: light, Dark: dark}, lightOK && darkOK
+    }
+    color, ok := extractColorValue(n)
+    return lipgloss.Color(color), ok
+}
+
+func extractColorValue(n configNode) (string, bool) {
+    color := n.requiredStr()
+    if color != "" && !colorPattern.MatchString(color) {
+        n.fail("expected a color like \"205\" or \"#ff8800\", got %q", color)
+        return "", false
+    }
+    return color, color != ""
+}
+
+// extractSpacing reads padding or a margin: a number for every side, or a
+// list of one to four numbers in the order lipgloss takes them, starting
+// at the top and going clockwise.
+func extractSpacing(n configNode) ([]int, bool) {
+    nodes := []configNode{n}
+    if _, ok := n.value.([]interface{}); ok {
+        if nodes = n.items(); len(nodes) == 0 || len(nodes) > 4 {
+            n.fail("expected one to four numbers")
+            return nil, false
+        }
+    }
+    sides := make([]int, len(nodes))
+    for i, node := range nodes {
+        v := node.number(0)
+        if v < 0 || v != float64(int(v)) {
+            node.fail("expected a whole number of cells, got %v", v)
+            return nil, false
+        }
+        sides[i] = int(v)
+    }
+    return sides, true
+}
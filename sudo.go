@@ -0,0 +1,87 @@
+package main
+
+import (
+    "fmt"
+    "strings"
+
+    "github.com/charmbracelet/bubbles/textinput"
+)
+
+// sudoMarker starts the password prompt sudo is told to print, so it can be
+// told apart from the command's output. sudo replaces %p with the user whose
+// password it wants.
+const sudoMarker = "[cmdtui] sudo password for "
+
+// elevate returns cmd run as root with sudo. sudo prints its password prompt
+// with the output, where askPassword picks it up, and reads the password
+// from the command's input. Commands that have the whole terminal, or run in
+// a tmux pane, leave sudo to ask on the terminal itself. Commands given a
+// stdin run on a pseudo-terminal, which sudo reads the password from rather
+// than the first line of their input.
+func (cmd command) elevate() command {
+    if !cmd.sudo {
+        return cmd
+    }
+    if cmd.stdin.isSet() && !cmd.interactive && cmd.tmuxTarget == "" {
+        cmd.pty = true
+    }
+    argv := []string{"sudo"}
+    if !cmd.interactive && cmd.tmuxTarget == "" {
+        argv = append(argv, "-p", sudoMarker+"%p\n")
+    }
+    if cmd.readsPassword() {
+        // Otherwise sudo reads the terminal, which also hides the password
+        argv = append(argv, "-S")
+    }
+    cmd.cmd = append(append(argv, "--"), cmd.cmd...)
+    return cmd
+}
+
+// readsPassword reports whether sudo reads the password of cmd from its
+// standard input, which then has to stay open for it.
+func (cmd command) readsPassword() bool {
+    return cmd.sudo && !cmd.interactive && cmd.tmuxTarget == "" && !cmd.pty && !cmd.stdin.isSet()
+}
+
+// askPassword switches the input to hidden typing for the sudo password of
+// j, if line is sudo asking for it, and reports whether it was.
+func (m *model) askPassword(j *job, line string) bool {
+    if !j.cmd.sudo || !strings.HasPrefix(line, sudoMarker) {
+        return false
+    }
+    user := strings.TrimPrefix(line, sudoMarker)
+    m.resetInput()
+    m.input.EchoMode = textinput.EchoPassword
+    m.input.Placeholder = fmt.Sprintf("Password of %s for sudo to run %s...", user, j.cmd.name)
+    m.input.Focus()
+    m.focus = focusInput
+    m.passwordJob = j.id
+    return true
+}
+
+// sendPassword hands the typed password to the job that asked for it. It is
+// never shown or kept.
+func (m *model) sendPassword(password string) {
+    if j := m.job(m.passwordJob); j != nil {
+        if err := j.sendInput(password); err != nil {
+            m.appendOutput(j.tab, fmt.Sprintf("Error sending the password to %s: %v\n", j.cmd.name, err))
+        }
+    }
+    m.resetInput()
+    m.focus = focusList
+}
+
+// elevation describes where cmd runs when it is not as the user on this
+// machine, like "sudo @prod", and is "" when it is. Buttons and the tabs
+// they ran in show it, so a command for production is hard to mistake for
+// a local one.
+func (cmd command) elevation() string {
+    var parts []string
+    if cmd.sudo {
+        parts = append(parts, "sudo")
+    }
+    if cmd.target != "" {
+        parts = append(parts, "@"+cmd.target)
+    }
+    return strings.Join(parts, " ")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+    "fmt"
+    "sort"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// allTags is picked to list every button again.
+const allTags = "(all)"
+
+// hasTag reports whether cmd is tagged with tag.
+func (cmd command) hasTag(tag string) bool {
+    for _, t := range cmd.tags {
+        if t == tag {
+            return true
+        }
+    }
+    return false
+}
+
+// pickTag asks for a tag in the fuzzy finder and narrows the list down to
+// the buttons that have it.
+func (m *model) pickTag() tea.Cmd {
+    counts := make(map[string]int)
+    for _, cmd := range m.configOrder() {
+        for _, tag := range cmd.tags {
+            counts[tag]++
+        }
+    }
+    if len(counts) == 0 {
+        m.appendOutput(m.currentTab, "No buttons have tags\n")
+        return nil
+    }
+    tags := make([]string, 0, len(counts))
+    for tag := range counts {
+        tags = append(tags, tag)
+    }
+    sort.Strings(tags)
+
+    candidates := []string{fmt.Sprintf("%s  every button", allTags)}
+    for _, tag := range tags {
+        candidates = append(candidates, fmt.Sprintf("%s  %d buttons", tag, counts[tag]))
+    }
+    p := placeholder{name: "tag", what: "tags", candidates: func(command, map[string]string) ([]string, error) {
+        return candidates, nil
+    }}
+    cmd := command{name: "tag filter", cmd: []string{"{tag}"}}
+    picker := &pickerCommand{cmd: cmd, placeholder: p}
+    return tea.Exec(picker, func(err error) tea.Msg {
+        return placeholderPickedMsg{cmd: cmd, placeholder: p, value: picker.value, err: err, then: func(m *model, cmd command) tea.Cmd {
+            return m.useTag(cmd.cmd[0])
+        }}
+    })
+}
+
+// useTag lists only the buttons tagged with tag, or all of them for allTags.
+func (m *model) useTag(tag string) tea.Cmd {
+    if tag == allTags {
+        tag = ""
+    }
+    m.tag = tag
+    m.list.Title = m.listTitle()
+    return m.sortCommands()
+}
+
+// listTitle names the active profile and tag above the list.
+func (m *model) listTitle() string {
+    title := "Buttons"
+    if m.profile >= 0 {
+        title += fmt.Sprintf(" (%s)", m.profiles[m.profile].name)
+    }
+    if m.tag != "" {
+        title += " #" + m.tag
+    }
+    return title
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+    "fmt"
+
+    "github.com/charmbracelet/bubbles/progress"
+    "github.com/charmbracelet/lipgloss"
+    "gopkg.in/yaml.v3"
+)
+
+// theme changes the built-in styles before the config's styles section
+// does, written the same way.
+type theme struct {
+    styles   string                  // The changes, as a styles section in YAML
+    progress *lipgloss.AdaptiveColor // Solid color of the progress bar, nil for the gradient
+}
+
+// themes are the themes the config's theme setting and --theme can pick.
+// high-contrast keeps to the black, white and brightest colors of the
+// terminal's palette, and sets the focused pane apart by more than color.
+var themes = map[string]theme{
+    "default": {},
+    "high-contrast": {
+        styles: `
+border: {border_foreground: {light: "0", dark: "15"}}
+focused_border: {border: thick, border_foreground: {light: "18", dark: "11"}}
+active_button: {foreground: {light: "15", dark: "0"}, background: {light: "18", dark: "11"}, bold: true}
+inactive_button: {foreground: {light: "0", dark: "15"}}
+elevated_button: {foreground: {light: "94", dark: "214"}}
+elevated: {foreground: {light: "94", dark: "214"}, bold: true}
+elevated_banner: {foreground: {light: "15", dark: "0"}, background: {light: "94", dark: "214"}}
+tab: {foreground: {light: "0", dark: "15"}}
+active_tab: {foreground: {light: "18", dark: "11"}, underline: true}
+tab_gap: {foreground: {light: "0", dark: "15"}}
+list_title: {foreground: {light: "15", dark: "0"}, background: {light: "18", dark: "11"}, bold: true}
+help_key: {foreground: {light: "0", dark: "15"}, bold: true}
+help_description: {foreground: {light: "0", dark: "15"}}
+spinner: {foreground: {light: "18", dark: "11"}}
+status: {foreground: {light: "0", dark: "15"}}
+error: {foreground: {light: "124", dark: "9"}, bold: true}
+stderr: {foreground: {light: "124", dark: "9"}}
+pinned_divider: {foreground: {light: "0", dark: "15"}}
+cheat_sheet: {border: thick, border_foreground: {light: "18", dark: "11"}}
+editor: {border: thick, border_foreground: {light: "18", dark: "11"}}
+run_header: {foreground: {light: "18", dark: "11"}}
+run_success: {foreground: {light: "22", dark: "10"}, bold: true}
+run_failure: {foreground: {light: "124", dark: "9"}}
+diff_added: {foreground: {light: "22", dark: "10"}}
+diff_removed: {foreground: {light: "124", dark: "9"}}
+diff_hunk: {foreground: {light: "18", dark: "14"}}
+table_header: {foreground: {light: "18", dark: "11"}, underline: true}
+markdown_heading: {foreground: {light: "18", dark: "11"}, underline: true}
+markdown_code: {foreground: {light: "0", dark: "15"}, background: {light: "254", dark: "236"}}
+markdown_quote: {foreground: {light: "0", dark: "15"}}
+`,
+        progress: &lipgloss.AdaptiveColor{Light: "18", Dark: "11"},
+    },
+}
+
+// themeFlag is the theme picked with --theme, which wins over the config's.
+var themeFlag string
+
+// extractTheme reads the theme setting, the name of one of themes.
+func extractTheme(n configNode) theme {
+    name := n.str("default")
+    if themeFlag != "" {
+        name = themeFlag
+    }
+    t, ok := themes[name]
+    if !ok {
+        n.fail("unknown theme %q, expected default or high-contrast", name)
+    }
+    return t
+}
+
+// styleChanges returns the changes the theme makes to the built-in styles.
+func (t theme) styleChanges() map[string][]styleSetter {
+    var raw map[string]interface{}
+    if err := yaml.Unmarshal([]byte(t.styles), &raw); err != nil {
+        panic(err)
+    }
+    r := newConfigReader("theme")
+    changes := extractStyles(r.root(raw))
+    if err := r.err(); err != nil {
+        panic(fmt.Sprintf("built-in theme: %v", err))
+    }
+    return changes
+}
+
+// progressBar returns a progress bar in the theme's colors.
+func (t theme) progressBar() progress.Model {
+    if t.progress == nil {
+        return progress.New(progress.WithDefaultGradient(), progress.WithWidth(40))
+    }
+    color := t.progress.Dark
+    if !lipgloss.HasDarkBackground() {
+        color = t.progress.Light
+    }
+    return progress.New(progress.WithSolidFill(color), progress.WithWidth(40))
+}
@@ -0,0 +1,50 @@
+package main
+
+import "time"
+
+// outputInterval is how often a job's output is passed on. Output read in
+// between is sent together, so a command printing thousands of lines a
+// second redraws the TUI about 30 times a second rather than for each line.
+const outputInterval = time.Second / 30
+
+// queue holds msg back until the next flushOutput, adding its lines to the
+// last message queued when they came from the same stream.
+func (j *job) queue(msg commandOutputMsg) {
+    j.queuedMu.Lock()
+    defer j.queuedMu.Unlock()
+    if n := len(j.queued); n > 0 && msg.notice == "" {
+        last := &j.queued[n-1]
+        if last.notice == "" && last.stderr == msg.stderr {
+            last.lines = append(last.lines, msg.lines...)
+            return
+        }
+    }
+    j.queued = append(j.queued, msg)
+}
+
+// flushOutput sends the messages queued since the last flush.
+func (j *job) flushOutput() {
+    j.queuedMu.Lock()
+    queued := j.queued
+    j.queued = nil
+    j.queuedMu.Unlock()
+    for _, msg := range queued {
+        j.events <- msg
+    }
+}
+
+// passOutput flushes the job's output every outputInterval until read is
+// closed, once all of it has been read, and then a last time.
+func (j *job) passOutput(read <-chan struct{}) {
+    ticker := time.NewTicker(outputInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            j.flushOutput()
+        case <-read:
+            j.flushOutput()
+            return
+        }
+    }
+}
@@ -0,0 +1,24 @@
+package main
+
+// tmux rewrites cmd to type it into its tmux pane and press enter, so it
+// runs in the shell already open there instead of under cmdtui. The pane is
+// anything tmux accepts for -t, such as "work:1.0". Only errors from tmux
+// itself show up in the output. Commands without a pane are returned
+// unchanged.
+func (cmd command) tmux() command {
+    if cmd.tmuxTarget == "" {
+        return cmd
+    }
+    line := cmd.shellLine()
+    if len(cmd.pipe) > 0 {
+        line += " | " + command{cmd: cmd.pipe}.shellLine()
+        cmd.pipe = nil
+    }
+    cmd.cmd = []string{"tmux", "send-keys", "-t", cmd.tmuxTarget, "-l", line, ";", "send-keys", "-t", cmd.tmuxTarget, "Enter"}
+    cmd.dir = ""
+    cmd.env = nil
+    // The pane has a terminal of its own
+    cmd.pty = false
+    cmd.interactive = false
+    return cmd
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "regexp"
+    "strings"
+    "time"
+
+    lua "github.com/yuin/gopher-lua"
+)
+
+// pathSegment is one step from the config root to a value: either a table
+// key or a list index. An anchor, such as a button's name, identifies list
+// entries in messages and when looking up line numbers.
+type pathSegment struct {
+    key    string
+    index  int
+    anchor string
+}
+
+// configReader collects validation errors while a config tree is turned
+// into a config, so every problem is reported at once instead of the first
+// bad value aborting the load.
+type configReader struct {
+    file   string
+    source []string // Lines of file, read lazily for line context
+    errs   []string
+}
+
+// configNode is a value in the config tree together with the path that led
+// to it.
+type configNode struct {
+    r     *configReader
+    path  []pathSegment
+    value interface{}
+}
+
+func newConfigReader(file string) *configReader {
+    return &configReader{file: file}
+}
+
+// root returns the node for the top-level config table.
+func (r *configReader) root(raw map[string]interface{}) configNode {
+    return configNode{r: r, value: raw}
+}
+
+// err returns all collected problems as a single error, or nil.
+func (r *configReader) err() error {
+    if len(r.errs) == 0 {
+        return nil
+    }
+    return errors.New(strings.Join(r.errs, "\n"))
+}
+
+// line makes a best effort to find the source line a path refers to by
+// scanning forward for each key or anchor in turn. It returns 0 when the
+// file cannot be read or nothing matches.
+func (r *configReader) line(path []pathSegment) int {
+    if r.source == nil {
+        data, err := os.ReadFile(r.file)
+        if err != nil {
+            return 0
+        }
+        r.source = strings.Split(string(data), "\n")
+    }
+
+    pos, found := 0, 0
+    for _, seg := range path {
+        var pattern *regexp.Regexp
+        switch {
+        case seg.anchor != "":
+            pattern = regexp.MustCompile(`(^|[\s"'=:])` + regexp.QuoteMeta(seg.anchor) + `(["']|\s*$)`)
+        case seg.key != "":
+            k := regexp.QuoteMeta(seg.key)
+            pattern = regexp.MustCompile(`(^|[\s{,])["']?` + k + `["']?\s*[:=]|^\s*\[\[?` + k + `\]\]?\s*$`)
+        default:
+            // Without an anchor there is no telling which entry of the list
+            // is meant, stop at the list itself
+            return found
+        }
+        matched := false
+        for i := pos; i < len(r.source); i++ {
+            if pattern.MatchString(r.source[i]) {
+                pos, found, matched = i, i+1, true
+                break
+            }
+        }
+        if !matched {
+            break
+        }
+    }
+    return found
+}
+
+// fail records a problem with n.
+func (n configNode) fail(format string, args ...interface{}) {
+    location := n.r.file
+    if line := n.r.line(n.path); line > 0 {
+        location = fmt.Sprintf("%s:%d", n.r.file, line)
+    }
+    n.r.errs = append(n.r.errs, fmt.Sprintf("%s: %s: %s", location, n.pathString(), fmt.Sprintf(format, args...)))
+}
+
+func (n configNode) pathString() string {
+    if len(n.path) == 0 {
+        return "config"
+    }
+    var b strings.Builder
+    for i, seg := range n.path {
+        switch {
+        case seg.anchor != "":
+            fmt.Fprintf(&b, "[%q]", seg.anchor)
+        case seg.key == "":
+            fmt.Fprintf(&b, "[%d]", seg.index+1)
+        default:
+            if i > 0 {
+                b.WriteByte('.')
+            }
+            b.WriteString(seg.key)
+        }
+    }
+    return b.String()
+}
+
+func (n configNode) child(seg pathSegment, value interface{}) configNode {
+    path := make([]pathSegment, len(n.path), len(n.path)+1)
+    copy(path, n.path)
+    return configNode{r: n.r, path: append(path, seg), value: value}
+}
+
+// key returns the child of a table node stored under name. Looking up a key
+// in a value that is not a table yields an unset node, the mismatch is
+// reported by whoever asked for the table itself.
+func (n configNode) key(name string) configNode {
+    table, _ := n.value.(map[string]interface{})
+    return n.child(pathSegment{key: name}, table[name])
+}
+
+// anchored returns n with its last path segment labelled by anchor.
+func (n configNode) anchored(anchor string) configNode {
+    if len(n.path) == 0 || anchor == "" {
+        return n
+    }
+    path := make([]pathSegment, len(n.path))
+    copy(path, n.path)
+    path[len(path)-1].anchor = anchor
+    n.path = path
+    return n
+}
+
+// isSet reports whether the value is present at all.
+func (n configNode) isSet() bool {
+    return n.value != nil
+}
+
+// table reports whether n holds a table, recording an error if it holds
+// something else. Unset values are not an error.
+func (n configNode) table() bool {
+    switch v := n.value.(type) {
+    case nil:
+        return false
+    case map[string]interface{}:
+        return true
+    case []interface{}:
+        // Empty Lua tables decode as lists
+        return len(v) == 0
+    }
+    n.fail("expected a table, got %s", describe(n.value))
+    return false
+}
+
+// keys returns the entries of a table node in no particular order.
+func (n configNode) keys() []string {
+    if !n.table() {
+        return nil
+    }
+    table, _ := n.value.(map[string]interface{})
+    names := make([]string, 0, len(table))
+    for name := range table {
+        names = append(names, name)
+    }
+    return names
+}
+
+// items returns the elements of a list node.
+func (n configNode) items() []configNode {
+    switch v := n.value.(type) {
+    case nil:
+        return nil
+    case []interface{}:
+        nodes := make([]configNode, len(v))
+        for i, item := range v {
+            nodes[i] = n.child(pathSegment{index: i}, item)
+        }
+        return nodes
+    case map[string]interface{}:
+        if len(v) == 0 {
+            return nil
+        }
+    }
+    n.fail("expected a list, got %s", describe(n.value))
+    return nil
+}
+
+// str returns a scalar value as a string, or def when unset.
+func (n configNode) str(def string) string {
+    switch v := n.value.(type) {
+    case nil:
+        return def
+    case string:
+        return v
+    case float64, int, int64, bool:
+        return fmt.Sprint(v)
+    }
+    n.fail("expected a string, got %s", describe(n.value))
+    return def
+}
+
+// requiredStr is like str but records an error when the value is missing
+// or empty.
+func (n configNode) requiredStr() string {
+    if !n.isSet() {
+        n.fail("missing required value")
+        return ""
+    }
+    s := n.str("")
+    if s == "" {
+        n.fail("must not be empty")
+    }
+    return s
+}
+
+// strs returns a list of scalars as strings.
+func (n configNode) strs() []string {
+    var strs []string
+    for _, item := range n.items() {
+        strs = append(strs, item.str(""))
+    }
+    return strs
+}
+
+// number returns a numeric value, or def when unset.
+func (n configNode) number(def float64) float64 {
+    switch v := n.value.(type) {
+    case nil:
+        return def
+    case float64:
+        return v
+    case int:
+        return float64(v)
+    case int64:
+        return float64(v)
+    }
+    n.fail("expected a number, got %s", describe(n.value))
+    return def
+}
+
+// positiveInt returns a whole number greater than zero, or def when unset.
+func (n configNode) positiveInt(def int) int {
+    v := n.number(float64(def))
+    if v <= 0 || v != float64(int(v)) {
+        n.fail("expected a positive whole number, got %v", v)
+        return def
+    }
+    return int(v)
+}
+
+// duration returns a length of time written like 90s or 1h30m, or def when
+// unset.
+func (n configNode) duration(def time.Duration) time.Duration {
+    s := n.str("")
+    if s == "" {
+        return def
+    }
+    d, err := time.ParseDuration(s)
+    if err != nil {
+        n.fail("expected a duration like 30s or 1h, got %q", s)
+        return def
+    }
+    return d
+}
+
+// boolean returns a true/false value, or def when unset.
+func (n configNode) boolean(def bool) bool {
+    switch v := n.value.(type) {
+    case nil:
+        return def
+    case bool:
+        return v
+    }
+    n.fail("expected true or false, got %s", describe(n.value))
+    return def
+}
+
+// regexp compiles a pattern value, returning nil when unset or invalid.
+func (n configNode) regexp() *regexp.Regexp {
+    pattern := n.str("")
+    if pattern == "" {
+        return nil
+    }
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        n.fail("invalid pattern: %v", err)
+        return nil
+    }
+    return re
+}
+
+// function returns a Lua function value, or nil when unset. Only Lua configs
+// can provide functions.
+func (n configNode) function() *lua.LFunction {
+    switch v := n.value.(type) {
+    case nil:
+        return nil
+    case *lua.LFunction:
+        return v
+    }
+    n.fail("expected a Lua function, got %s", describe(n.value))
+    return nil
+}
+
+// describe names the type of a config value for error messages.
+func describe(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return "nothing"
+    case string:
+        return fmt.Sprintf("string %q", v)
+    case bool:
+        return fmt.Sprintf("boolean %v", v)
+    case float64, int, int64:
+        return fmt.Sprintf("number %v", v)
+    case []interface{}:
+        return "a list"
+    case map[string]interface{}:
+        return "a table"
+    case *lua.LFunction:
+        return "a function"
+    }
+    return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+    "strings"
+    "unicode"
+
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// viState is the input as it was before a change, for undoing it with u.
+type viState struct {
+    value  string
+    cursor int
+}
+
+// viKey edits the input like vi for vi_input. Esc goes from insert mode to
+// normal mode, where keys move the cursor and change the text instead of
+// typing, until i, a or a change goes back. It reports whether msg was
+// taken: in normal mode that is every key that would type something, so
+// none of them reach the global bindings. Enter still runs the command.
+func (m *model) viKey(msg tea.KeyMsg) bool {
+    if !m.viNormal {
+        if msg.Type != tea.KeyEsc {
+            return false
+        }
+        // Like vi, the cursor steps back onto the last character typed
+        m.viNormal = true
+        m.input.SetCursor(m.input.Position() - 1)
+        return true
+    }
+    switch msg.Type {
+    case tea.KeyRunes:
+        if msg.Paste || msg.Alt {
+            break
+        }
+        // Keys typed quickly come together. Once one of them goes to
+        // insert mode the rest are typed
+        for i, r := range msg.Runes {
+            if !m.viNormal {
+                value, pos := []rune(m.input.Value()), m.input.Position()
+                typed := msg.Runes[i:]
+                m.input.SetValue(string(value[:pos]) + string(typed) + string(value[pos:]))
+                m.input.SetCursor(pos + len(typed))
+                break
+            }
+            m.viCommand(string(r))
+        }
+    case tea.KeySpace, tea.KeyRight:
+        m.viCommand("l")
+    case tea.KeyLeft, tea.KeyBackspace:
+        m.viCommand("h")
+    case tea.KeyHome:
+        m.viCommand("0")
+    case tea.KeyEnd:
+        m.viCommand("$")
+    case tea.KeyEsc:
+        m.viPending = ""
+    default:
+        return false
+    }
+    return true
+}
+
+// viCommand runs a normal mode command, keeping the keys in viPending while
+// it needs more of them, such as the c of cw or the f of fx. A command that
+// turns out to be unknown is dropped with its keys.
+func (m *model) viCommand(k string) {
+    seq := m.viPending + k
+    m.viPending = ""
+    value := []rune(m.input.Value())
+    pos := min(m.input.Position(), max(len(value)-1, 0))
+
+    if op := seq[:1]; strings.Contains("cdy", op) {
+        rest := seq[1:]
+        if rest == "" {
+            m.viPending = seq
+            return
+        }
+        // A doubled operator works on the whole line
+        if rest == op {
+            m.viOperate(op, value, 0, len(value))
+            return
+        }
+        // cw changes to the end of the word, not the start of the next
+        if op == "c" && (rest == "w" || rest == "W") && pos < len(value) && viClass(value[pos], true) != 0 {
+            _, to, _ := viObject(value, pos, false, rune(rest[0]))
+            m.viOperate(op, value, pos, to)
+            return
+        }
+        from, to, status := viTarget(value, pos, rest, true)
+        switch status {
+        case viMore:
+            m.viPending = seq
+        case viDone:
+            m.viOperate(op, value, from, to)
+        }
+        return
+    }
+
+    switch seq {
+    case "i":
+        m.viInsert(pos)
+    case "a":
+        m.viInsert(min(pos+1, len(value)))
+    case "I":
+        m.viInsert(viFirstNonBlank(value))
+    case "A":
+        m.viInsert(len(value))
+    case "x":
+        m.viOperate("d", value, pos, min(pos+1, len(value)))
+    case "X":
+        if pos > 0 {
+            m.viOperate("d", value, pos-1, pos)
+        }
+    case "s":
+        m.viOperate("c", value, pos, min(pos+1, len(value)))
+    case "S":
+        m.viOperate("c", value, 0, len(value))
+    case "D":
+        m.viOperate("d", value, pos, len(value))
+    case "C":
+        m.viOperate("c", value, pos, len(value))
+    case "p", "P":
+        if m.viRegister == "" {
+            return
+        }
+        at := pos
+        if seq == "p" && len(value) > 0 {
+            at++
+        }
+        m.viSave()
+        paste := []rune(m.viRegister)
+        m.input.SetValue(string(value[:at]) + string(paste) + string(value[at:]))
+        m.input.SetCursor(at + len(paste) - 1)
+    case "~":
+        if pos < len(value) {
+            m.viSave()
+            r := value[pos]
+            if unicode.IsUpper(r) {
+                value[pos] = unicode.ToLower(r)
+            } else {
+                value[pos] = unicode.ToUpper(r)
+            }
+            m.input.SetValue(string(value))
+            m.input.SetCursor(min(pos+1, len(value)-1))
+        }
+    case "u":
+        if n := len(m.viUndo); n > 0 {
+            last := m.viUndo[n-1]
+            m.viUndo = m.viUndo[:n-1]
+            m.input.SetValue(last.value)
+            m.input.SetCursor(min(last.cursor, max(len([]rune(last.value))-1, 0)))
+        }
+    case "r":
+        m.viPending = seq
+    default:
+        if len(seq) == 2 && seq[0] == 'r' {
+            if pos < len(value) {
+                m.viSave()
+                value[pos] = []rune(seq)[1]
+                m.input.SetValue(string(value))
+                m.input.SetCursor(pos)
+            }
+            return
+        }
+        _, to, status := viTarget(value, pos, seq, false)
+        switch status {
+        case viMore:
+            m.viPending = seq
+        case viDone:
+            m.input.SetCursor(min(to, max(len(value)-1, 0)))
+        }
+    }
+}
+
+// viInsert goes to insert mode with the cursor at pos, keeping the input as
+// it was for u.
+func (m *model) viInsert(pos int) {
+    m.viSave()
+    m.viNormal = false
+    m.input.SetCursor(pos)
+}
+
+// viOperate deletes, changes or yanks the runes of value from from to to,
+// as op, d, c or y, says. What it takes is kept for p and P.
+func (m *model) viOperate(op string, value []rune, from, to int) {
+    if from > to {
+        from, to = to, from
+    }
+    from, to = max(from, 0), min(to, len(value))
+    if from == to && op != "c" {
+        return
+    }
+    m.viRegister = string(value[from:to])
+    if op == "y" {
+        m.input.SetCursor(from)
+        return
+    }
+    m.viSave()
+    rest := append(value[:from:from], value[to:]...)
+    m.input.SetValue(string(rest))
+    if op == "c" {
+        m.viNormal = false
+        m.input.SetCursor(from)
+        return
+    }
+    m.input.SetCursor(min(from, max(len(rest)-1, 0)))
+}
+
+// viSave keeps the input as it is for u to go back to.
+func (m *model) viSave() {
+    m.viUndo = append(m.viUndo, viState{m.input.Value(), m.input.Position()})
+}
+
+// viResult tells whether a motion or text object was found, needs more
+// keys, or doesn't exist.
+type viResult int
+
+const (
+    viNone viResult = iota
+    viMore
+    viDone
+)
+
+// viTarget returns the runes of value a motion or, after an operator, a
+// text object covers from the cursor at pos: where the cursor goes for a
+// motion, and the range from to to for an operator to work on.
+func viTarget(value []rune, pos int, keys string, operator bool) (int, int, viResult) {
+    n := len(value)
+    first := keys[:1]
+    if len(keys) > 1 && !strings.Contains("fFtTia", first) {
+        return 0, 0, viNone
+    }
+    switch first {
+    case "h":
+        return pos, max(pos-1, 0), viDone
+    case "l":
+        return pos, min(pos+1, n), viDone
+    case "0":
+        return pos, 0, viDone
+    case "^":
+        return pos, viFirstNonBlank(value), viDone
+    case "$":
+        return pos, n, viDone
+    case "w", "W":
+        return pos, viWordForward(value, pos, first == "W"), viDone
+    case "b", "B":
+        return pos, viWordBack(value, pos, first == "B"), viDone
+    case "e", "E":
+        to := viWordEnd(value, pos, first == "E")
+        if operator {
+            // e takes the last character with it
+            to++
+        }
+        return pos, min(to, n), viDone
+    case "f", "F", "t", "T":
+        if len(keys) == 1 {
+            return 0, 0, viMore
+        }
+        to, ok := viFind(value, pos, first, []rune(keys)[1])
+        if !ok {
+            return 0, 0, viNone
+        }
+        if operator && (first == "f" || first == "t") {
+            to++
+        }
+        return pos, to, viDone
+    case "i", "a":
+        if !operator {
+            return 0, 0, viNone
+        }
+        if len(keys) == 1 {
+            return 0, 0, viMore
+        }
+        from, to, ok := viObject(value, pos, first == "a", []rune(keys)[1])
+        if !ok {
+            return 0, 0, viNone
+        }
+        return from, to, viDone
+    }
+    return 0, 0, viNone
+}
+
+// viClass is the kind of character r is for word motions: 0 for blanks, 1
+// for letters, digits and _, 2 for other punctuation. For WORDs everything
+// but blanks is 1.
+func viClass(r rune, bigWord bool) int {
+    switch {
+    case unicode.IsSpace(r):
+        return 0
+    case bigWord || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+        return 1
+    }
+    return 2
+}
+
+func viFirstNonBlank(value []rune) int {
+    for i, r := range value {
+        if !unicode.IsSpace(r) {
+            return i
+        }
+    }
+    return 0
+}
+
+// viWordForward returns the start of the word after the one at pos, for w.
+func viWordForward(value []rune, pos int, bigWord bool) int {
+    n := len(value)
+    if pos >= n {
+        return n
+    }
+    class := viClass(value[pos], bigWord)
+    for pos < n && class != 0 && viClass(value[pos], bigWord) == class {
+        pos++
+    }
+    for pos < n && viClass(value[pos], bigWord) == 0 {
+        pos++
+    }
+    return pos
+}
+
+// viWordBack returns the start of the word at pos, or of the one before when
+// the cursor is already there, for b.
+func viWordBack(value []rune, pos int, bigWord bool) int {
+    pos--
+    for pos > 0 && viClass(value[pos], bigWord) == 0 {
+        pos--
+    }
+    if pos <= 0 {
+        return 0
+    }
+    class := viClass(value[pos], bigWord)
+    for pos > 0 && viClass(value[pos-1], bigWord) == class {
+        pos--
+    }
+    return pos
+}
+
+// viWordEnd returns the last character of the word at pos, or of the next
+// one when the cursor is already there, for e.
+func viWordEnd(value []rune, pos int, bigWord bool) int {
+    n := len(value)
+    pos++
+    for pos < n && viClass(value[pos], bigWord) == 0 {
+        pos++
+    }
+    if pos >= n {
+        return max(n-1, 0)
+    }
+    class := viClass(value[pos], bigWord)
+    for pos+1 < n && viClass(value[pos+1], bigWord) == class {
+        pos++
+    }
+    return pos
+}
+
+// viFind returns where f, F, t or T with the character r goes from pos.
+func viFind(value []rune, pos int, motion string, r rune) (int, bool) {
+    switch motion {
+    case "f", "t":
+        for i := pos + 1; i < len(value); i++ {
+            if value[i] == r {
+                if motion == "t" {
+                    return i - 1, true
+                }
+                return i, true
+            }
+        }
+    case "F", "T":
+        for i := pos - 1; i >= 0; i-- {
+            if value[i] == r {
+                if motion == "T" {
+                    return i + 1, true
+                }
+                return i, true
+            }
+        }
+    }
+    return 0, false
+}
+
+// viBrackets pairs each bracket text objects take with its other half.
+var viBrackets = map[rune][2]rune{
+    '(': {'(', ')'}, ')': {'(', ')'}, 'b': {'(', ')'},
+    '[': {'[', ']'}, ']': {'[', ']'},
+    '{': {'{', '}'}, '}': {'{', '}'}, 'B': {'{', '}'},
+    '<': {'<', '>'}, '>': {'<', '>'},
+}
+
+// viObject returns the range of the text object obj around pos: inside it,
+// or all of it with around. Words, WORDs, quotes and brackets are known.
+func viObject(value []rune, pos int, around bool, obj rune) (int, int, bool) {
+    switch obj {
+    case 'w', 'W':
+        if pos >= len(value) {
+            return 0, 0, false
+        }
+        class := viClass(value[pos], obj == 'W')
+        from, to := pos, pos+1
+        for from > 0 && viClass(value[from-1], obj == 'W') == class {
+            from--
+        }
+        for to < len(value) && viClass(value[to], obj == 'W') == class {
+            to++
+        }
+        if around {
+            // The blanks after the word go with it, or else the ones before
+            end := to
+            for end < len(value) && unicode.IsSpace(value[end]) {
+                end++
+            }
+            if end == to {
+                for from > 0 && unicode.IsSpace(value[from-1]) {
+                    from--
+                }
+            }
+            to = end
+        }
+        return from, to, true
+    case '"', '\'', '`':
+        // Quotes pair up from the start of the line. The pair around the
+        // cursor is taken, or else the first one after it
+        var quotes []int
+        for i, r := range value {
+            if r == obj {
+                quotes = append(quotes, i)
+            }
+        }
+        for i := 0; i+1 < len(quotes); i += 2 {
+            open, close := quotes[i], quotes[i+1]
+            if pos > close {
+                continue
+            }
+            if around {
+                return open, close + 1, true
+            }
+            return open + 1, close, true
+        }
+        return 0, 0, false
+    }
+    pair, ok := viBrackets[obj]
+    if !ok {
+        return 0, 0, false
+    }
+    open, depth := -1, 0
+    for i := min(pos, len(value)-1); i >= 0; i-- {
+        if value[i] == pair[1] && i != pos {
+            depth++
+        } else if value[i] == pair[0] {
+            if depth == 0 {
+                open = i
+                break
+            }
+            depth--
+        }
+    }
+    if open < 0 {
+        return 0, 0, false
+    }
+    depth = 0
+    for i := open + 1; i < len(value); i++ {
+        if value[i] == pair[0] {
+            depth++
+        } else if value[i] == pair[1] {
+            if depth > 0 {
+                depth--
+                continue
+            }
+            if around {
+                return open, i + 1, true
+            }
+            return open + 1, i, true
+        }
+    }
+    return 0, 0, false
+}
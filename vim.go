@@ -0,0 +1,56 @@
+package main
+
+import (
+    tea "github.com/charmbracelet/bubbletea"
+)
+
+// vimMotion moves through the output for the keys vim_navigation adds: gg
+// and G to the top and bottom, ctrl+d and ctrl+u half a page down and up,
+// zz to center the current line, and za to fold the current run, the z of
+// fold being taken. It reports whether msg was one of them, or the first
+// key of one.
+func (m *model) vimMotion(msg tea.KeyMsg) bool {
+    t := &m.tabs[m.currentTab]
+    v := &t.viewport
+    pending := m.vimPending
+    m.vimPending = ""
+    switch pending + msg.String() {
+    case "g", "z":
+        m.vimPending = msg.String()
+    case "gg":
+        v.SetYOffset(0)
+    case "G":
+        v.GotoBottom()
+    case "ctrl+d":
+        v.SetYOffset(v.YOffset + v.Height/2)
+    case "ctrl+u":
+        v.SetYOffset(v.YOffset - v.Height/2)
+    case "zz":
+        line := t.currentLine()
+        t.atLine = line
+        v.SetYOffset(line - v.Height/2)
+    case "za":
+        m.toggleFold()
+    default:
+        // Like vim, a key that doesn't finish a motion is dropped with it
+        return pending != ""
+    }
+    m.syncScroll()
+    return true
+}
+
+// jumpTo scrolls the tab so line is at the top, and makes it the current
+// line.
+func (t *outputTab) jumpTo(line int) {
+    t.atLine = line
+    t.viewport.SetYOffset(line)
+}
+
+// currentLine returns the line the last jump went to while it is in view,
+// otherwise the top line.
+func (t *outputTab) currentLine() int {
+    if t.atLine >= t.viewport.YOffset && t.atLine < t.viewport.YOffset+t.viewport.Height {
+        return t.atLine
+    }
+    return t.viewport.YOffset
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+
+    tea "github.com/charmbracelet/bubbletea"
+    "github.com/fsnotify/fsnotify"
+)
+
+// pathsChangedMsg lists the files that changed under the paths buttons
+// watch, gathered over watchDebounce.
+type pathsChangedMsg struct {
+    paths []string
+}
+
+// watchDebounce collects the burst of changes a save or a checkout makes
+// into a single re-run.
+const watchDebounce = 300 * time.Millisecond
+
+// pathWatcher watches the paths buttons list in watch_paths. Directories are
+// watched with everything below them, except hidden directories and
+// node_modules.
+type pathWatcher struct {
+    watcher *fsnotify.Watcher
+    events  chan tea.Msg
+
+    mu   sync.Mutex
+    dirs map[string]bool // Directories currently watched
+}
+
+func newPathWatcher() (*pathWatcher, error) {
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        return nil, err
+    }
+    w := &pathWatcher{watcher: watcher, events: make(chan tea.Msg), dirs: make(map[string]bool)}
+    go w.run()
+    return w, nil
+}
+
+// watchedPaths returns the absolute paths cmd watches. Relative paths are
+// taken from the button's working directory.
+func (cmd command) watchedPaths() []string {
+    dir := cmd.expand().dir
+    var paths []string
+    for _, path := range cmd.watchPaths {
+        if !filepath.IsAbs(path) && dir != "" {
+            path = filepath.Join(dir, path)
+        }
+        if abs, err := filepath.Abs(path); err == nil {
+            paths = append(paths, abs)
+        }
+    }
+    return paths
+}
+
+// watch replaces the watched paths. Paths that don't exist are skipped.
+func (w *pathWatcher) watch(paths []string) {
+    dirs := make(map[string]bool)
+    for _, path := range paths {
+        info, err := os.Stat(path)
+        if err != nil {
+            continue
+        }
+        if !info.IsDir() {
+            // Editors often replace files, which only shows on the directory
+            dirs[filepath.Dir(path)] = true
+            continue
+        }
+        filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+            if err != nil || !d.IsDir() {
+                return nil
+            }
+            if p != path && skipWatchDir(d.Name()) {
+                return filepath.SkipDir
+            }
+            dirs[p] = true
+            return nil
+        })
+    }
+
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    for dir := range w.dirs {
+        if !dirs[dir] {
+            w.watcher.Remove(dir)
+        }
+    }
+    for dir := range dirs {
+        if !w.dirs[dir] {
+            w.watcher.Add(dir)
+        }
+    }
+    w.dirs = dirs
+}
+
+// skipWatchDir reports whether a directory below a watched one is left out.
+func skipWatchDir(name string) bool {
+    return strings.HasPrefix(name, ".") || name == "node_modules"
+}
+
+func (w *pathWatcher) run() {
+    changed := make(map[string]bool)
+    var debounce <-chan time.Time
+    for {
+        select {
+        case event, ok := <-w.watcher.Events:
+            if !ok {
+                return
+            }
+            if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+                continue
+            }
+            if event.Has(fsnotify.Create) {
+                // Watch directories created inside watched ones
+                if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !skipWatchDir(info.Name()) {
+                    w.mu.Lock()
+                    if w.dirs[filepath.Dir(event.Name)] {
+                        w.dirs[event.Name] = true
+                        w.watcher.Add(event.Name)
+                    }
+                    w.mu.Unlock()
+                }
+            }
+            changed[event.Name] = true
+            debounce = time.After(watchDebounce)
+        case <-debounce:
+            debounce = nil
+            msg := pathsChangedMsg{}
+            for path := range changed {
+                msg.paths = append(msg.paths, path)
+            }
+            changed = make(map[string]bool)
+            w.events <- msg
+        case _, ok := <-w.watcher.Errors:
+            if !ok {
+                return
+            }
+        }
+    }
+}
+
+// wait returns a tea.Cmd that delivers the next batch of changes.
+func (w *pathWatcher) wait() tea.Cmd {
+    return func() tea.Msg {
+        return <-w.events
+    }
+}
+
+// watchButtonPaths points the path watcher at the watch_paths of the
+// buttons in the list.
+func (m *model) watchButtonPaths() {
+    if m.paths == nil {
+        return
+    }
+    var paths []string
+    for _, cmd := range m.commands {
+        paths = append(paths, cmd.watchedPaths()...)
+    }
+    m.paths.watch(paths)
+}
+
+// pathsChanged re-runs the buttons watching any of the changed paths. A
+// button that is still running is re-run once it finishes.
+func (m *model) pathsChanged(msg pathsChangedMsg) tea.Cmd {
+    var cmds []tea.Cmd
+    for _, cmd := range m.commands {
+        changed := watchedChange(cmd.watchedPaths(), msg.paths)
+        if changed == "" || cmd.prompt {
+            continue
+        }
+        if m.isRunning(cmd.name) {
+            m.rerun[cmd.name] = true
+            continue
+        }
+        if wd, err := os.Getwd(); err == nil {
+            if rel, err := filepath.Rel(wd, changed); err == nil && !strings.HasPrefix(rel, "..") {
+                changed = rel
+            }
+        }
+        m.appendOutput(m.currentTab, fmt.Sprintf("%s changed, re-running %s\n", changed, cmd.name))
+        cmds = append(cmds, m.runButton(cmd))
+    }
+    return tea.Batch(cmds...)
+}
+
+// watchedChange returns one of the changed paths that is, or is below, one
+// of the watched paths, or "".
+func watchedChange(watched, changed []string) string {
+    for _, path := range changed {
+        for _, w := range watched {
+            if path == w || strings.HasPrefix(path, w+string(filepath.Separator)) {
+                return path
+            }
+        }
+    }
+    return ""
+}
+
+// isRunning reports whether a job started by the button called name is
+// still running.
+func (m *model) isRunning(name string) bool {
+    for _, j := range m.jobs {
+        if j.cmd.name == name {
+            return true
+        }
+    }
+    return false
+}